@@ -2,16 +2,62 @@ package http
 
 import (
 	"food-delivery/internal/auth/handler"
+	"food-delivery/pkg/middlewares"
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	defhttp "net/http"
 )
 
-func InitRoutes(r *mux.Router, authHandler *handler.AuthHandler) {
+func InitRoutes(r *mux.Router, authHandler *handler.AuthHandler, client *redis.Client) {
+	// Открытые ключи для проверки токенов другими модулями публикуются в корне, по стандартному пути
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+
+	// Эндпоинты модулей доступны под префиксом /api
+	api := r.PathPrefix("/api").Subrouter()
+
 	// Эндпоинты модуля auth
-	r.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
-	r.HandleFunc("/auth/confirm-email", authHandler.ConfirmEmail).Methods("GET")
-	r.HandleFunc("/auth/sign-in", authHandler.SignIn).Methods("POST")
-	r.HandleFunc("/auth/sign-out", authHandler.SignOut).Methods("POST")
-	r.HandleFunc("/auth/refresh", authHandler.RefreshTokens).Methods("POST")
+	api.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
+	api.HandleFunc("/auth/confirm-email", authHandler.ConfirmEmail).Methods("GET")
+	api.HandleFunc("/auth/resend-code", authHandler.ResendCode).Methods("POST")
+	api.HandleFunc("/auth/confirm-phone", authHandler.ConfirmPhone).Methods("POST")
+	api.HandleFunc("/auth/phone/resend", authHandler.ResendPhoneCode).Methods("POST")
+	api.HandleFunc("/auth/sign-in", authHandler.SignIn).Methods("POST")
+	api.HandleFunc("/auth/phone/request-code", authHandler.RequestPhoneSignInCode).Methods("POST")
+	api.HandleFunc("/auth/phone/sign-in", authHandler.SignInByPhone).Methods("POST")
+	api.HandleFunc("/auth/oidc/{provider}/sign-in", authHandler.OIDCSignIn).Methods("GET")
+	api.HandleFunc("/auth/oidc/{provider}/callback", authHandler.OIDCCallback).Methods("GET")
+	// Эндпоинты, авторизуемые по refresh cookie, требуют CSRF токен (double-submit)
+	api.Handle("/auth/sign-out", middlewares.CSRFMiddleware(defhttp.HandlerFunc(authHandler.SignOut))).Methods("POST")
+	api.Handle("/auth/refresh", middlewares.CSRFMiddleware(defhttp.HandlerFunc(authHandler.RefreshTokens))).Methods("POST")
+	// Ссылка из письма открывает страницу подтверждения, сессии завершаются только по POST
+	api.HandleFunc("/auth/not-me", authHandler.NotMePage).Methods("GET")
+	api.HandleFunc("/auth/not-me", authHandler.NotMe).Methods("POST")
+	api.HandleFunc("/auth/forgot-password", authHandler.ForgotPassword).Methods("POST")
+	api.HandleFunc("/auth/reset-password", authHandler.ResetPassword).Methods("POST")
+	api.HandleFunc("/auth/2fa/sign-in", authHandler.SignInMFA).Methods("POST")
+
+	// Эндпоинты модуля auth, доступные только с действительным access токеном
+	protected := api.PathPrefix("/auth").Subrouter()
+	protected.Use(middlewares.AuthMiddleware(client))
+	protected.HandleFunc("/sessions", authHandler.GetSessions).Methods("GET")
+	protected.HandleFunc("/sessions/{id}", authHandler.DeleteSession).Methods("DELETE")
+	protected.HandleFunc("/sign-out-all", authHandler.SignOutAll).Methods("POST")
+	protected.HandleFunc("/change-password", authHandler.ChangePassword).Methods("POST")
+	protected.HandleFunc("/change-email", authHandler.ChangeEmail).Methods("POST")
+	protected.HandleFunc("/change-email/confirm", authHandler.ConfirmEmailChange).Methods("POST")
+	protected.HandleFunc("/login-history", authHandler.GetLoginHistory).Methods("GET")
+	protected.HandleFunc("/me", authHandler.DeleteAccount).Methods("DELETE")
+	protected.HandleFunc("/me/deletion-code", authHandler.SendDeletionCode).Methods("POST")
+	protected.HandleFunc("/2fa/enroll", authHandler.EnrollTOTP).Methods("POST")
+	protected.HandleFunc("/2fa/verify", authHandler.VerifyTOTP).Methods("POST")
+
+	// Эндпоинты администратора
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middlewares.AuthMiddleware(client), middlewares.RequireRole("admin"))
+	admin.HandleFunc("/users/{id}/sign-out-all", authHandler.AdminSignOutUser).Methods("POST")
+	admin.HandleFunc("/users/{id}/status", authHandler.AdminChangeUserStatus).Methods("PUT")
+	admin.HandleFunc("/users/{id}/presence", authHandler.AdminGetPresence).Methods("GET")
+	admin.HandleFunc("/login-history", authHandler.AdminLoginHistory).Methods("GET")
 
 	// Эндпоинты модуля restaurant
 	//r.HandleFunc()...
@@ -0,0 +1,115 @@
+// Утилита breach-corpus собирает файл базы утёкших паролей для pkg/passpolicy.
+//
+// Из дампа Have I Been Pwned (строки "SHA1:количество", отсортированные по хешу):
+//
+//	go run ./cmd/breach-corpus -in pwned-passwords-sha1-ordered-by-hash.txt -out configs/breached.bin
+//
+// Из списка паролей в открытом виде (по одному на строку, список целиком загружается в память):
+//
+//	go run ./cmd/breach-corpus -plain -in rockyou.txt -out configs/breached.bin
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"food-delivery/pkg/passpolicy"
+	deflog "log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "", "входной файл")
+	out := flag.String("out", "", "файл базы утёкших паролей")
+	plain := flag.Bool("plain", false, "входной файл содержит пароли в открытом виде")
+	prefix := flag.Int("prefix", passpolicy.DefaultCorpusPrefix, "длина сохраняемого префикса SHA-1 в байтах")
+	flag.Parse()
+
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	count, err := build(*in, *out, *plain, *prefix)
+	if err != nil {
+		deflog.Fatalf("ошибка сборки базы утёкших паролей: %s", err)
+	}
+
+	fmt.Printf("записано хешей: %d\n", count)
+}
+
+func build(inPath, outPath string, plain bool, prefix int) (int64, error) {
+	input, err := os.Open(inPath)
+	if err != nil {
+		return 0, err
+	}
+	defer input.Close()
+
+	output, err := os.Create(outPath)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+
+	writer, err := passpolicy.NewCorpusWriter(output, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if plain {
+		// Хеши паролей из открытого списка идут не по порядку: собираем и сортируем их в памяти
+		var hashes [][]byte
+		for scanner.Scan() {
+			if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+				sum := sha1.Sum([]byte(line))
+				hashes = append(hashes, sum[:])
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return 0, err
+		}
+
+		sort.Slice(hashes, func(i, j int) bool {
+			return bytes.Compare(hashes[i], hashes[j]) < 0
+		})
+		for _, hash := range hashes {
+			if err = writer.Add(hash); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		line := 0
+		for scanner.Scan() {
+			line++
+			value, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+			if value == "" {
+				continue
+			}
+
+			hash, err := hex.DecodeString(value)
+			if err != nil || len(hash) != sha1.Size {
+				return 0, fmt.Errorf("строка %d: некорректный SHA-1 хеш", line)
+			}
+			if err = writer.Add(hash); err != nil {
+				return 0, fmt.Errorf("строка %d: %v", line, err)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = writer.Flush(); err != nil {
+		return 0, err
+	}
+
+	return writer.Count(), nil
+}
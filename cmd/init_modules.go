@@ -5,15 +5,42 @@ import (
 	"food-delivery/internal/auth/handler"
 	"food-delivery/internal/auth/repository"
 	"food-delivery/internal/auth/service"
+	"food-delivery/pkg/hasher"
 	"food-delivery/pkg/logger"
+	"food-delivery/pkg/mailer"
+	"food-delivery/pkg/oidc"
+	"food-delivery/pkg/passpolicy"
+	"food-delivery/pkg/sms"
 	"github.com/redis/go-redis/v9"
+	"os"
+	"time"
 )
 
-func initAuthModule(db *sql.DB, client *redis.Client, log *logger.Logger) *handler.AuthHandler {
+func initAuthModule(db *sql.DB, client *redis.Client, log *logger.Logger) (*handler.AuthHandler, error) {
+	smsSender := sms.NewFileSender(os.Getenv("SMS_LOG_PATH"), log)
 	authRepository := repository.NewAuthRepository(db, log)
-	authService := service.NewAuthService(authRepository, client, log)
-	authHandler := handler.NewAuthHandler(authService, log)
-	return authHandler
+	oidcClients := oidc.LoadClients(nil)
+	passwordHasher, err := hasher.LoadFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	passwordPolicy, err := passpolicy.LoadFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	emailSender, err := mailer.LoadFromEnv(log)
+	if err != nil {
+		return nil, err
+	}
+	authService := service.NewAuthService(authRepository, client, smsSender, oidcClients, passwordHasher, passwordPolicy,
+		emailSender, log)
+	authService.StartAnonymizer(time.Hour) // Обезличивание удалённых аккаунтов после срока ожидания
+	cookieConfig, err := handler.LoadCookieConfig()
+	if err != nil {
+		return nil, err
+	}
+	authHandler := handler.NewAuthHandler(authService, cookieConfig, log)
+	return authHandler, nil
 }
 
 //func initRestaurantModule(db, client, log) {
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"food-delivery/api/http"
 	"food-delivery/internal/database"
+	"food-delivery/pkg/jwtkeys"
 	"food-delivery/pkg/logger"
+	"food-delivery/pkg/middlewares"
+	"food-delivery/pkg/utils"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	deflog "log"
@@ -33,6 +36,20 @@ func main() {
 	// Закрытие логгера при завершении работы программы
 	defer log.Close()
 
+	// Загрузка ключей подписи JWT
+	keyRing, err := jwtkeys.LoadFromEnv()
+	if err != nil {
+		log.Error("ошибка загрузки ключей подписи JWT:", err)
+		return
+	}
+	jwtkeys.SetDefault(keyRing)
+
+	// Загрузка ключа хеширования токенов (refresh токены, коды восстановления, state OIDC)
+	if err = utils.LoadTokenHashKey(); err != nil {
+		log.Error("ошибка загрузки ключа хеширования токенов:", err)
+		return
+	}
+
 	// Подключение к базе данных PostgreSQL
 	db, err := database.ConnectPsql()
 	if err != nil {
@@ -54,14 +71,32 @@ func main() {
 	log.Info("успешное подключение к Redis")
 
 	// Инициализация обработчиков
-	authHandler := initAuthModule(db, client, log)
+	authHandler, err := initAuthModule(db, client, log)
+	if err != nil {
+		log.Error("ошибка инициализации модуля auth:", err)
+		return
+	}
 	//restaurantHandler := initRestaurantModule(db, client, log)
 
+	// Список доверенных прокси и заголовок с адресом клиента, который они записывают
+	trustedProxies, err := middlewares.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Error("ошибка разбора TRUSTED_PROXIES:", err)
+		return
+	}
+	proxyHeader, err := middlewares.ParseForwardedHeader(os.Getenv("TRUSTED_PROXY_HEADER"))
+	if err != nil {
+		log.Error("ошибка разбора TRUSTED_PROXY_HEADER:", err)
+		return
+	}
+
 	// Инициализация маршрутизатора
 	r := mux.NewRouter()
+	// Реальный IP-адрес клиента определяется для всех обработчиков
+	r.Use(middlewares.ClientIPMiddleware(trustedProxies, proxyHeader))
 
 	// Инициализация маршрутов
-	http.InitRoutes(r, authHandler)
+	http.InitRoutes(r, authHandler, client)
 
 	// Запуск HTTP-сервера
 	err = defhttp.ListenAndServe(fmt.Sprintf(":%v", port), r)
@@ -86,11 +121,6 @@ func main() {
 	Админ может изменять данные пользователя, включая роль:
 	PUT /api/admin/update-user/{id} — обновление данных пользователя (только для админов)
 
-
-	Создать middleware для проверки роли admin:
-	1.Проверка JWT токена.
-	2.Проверка, что роль пользователя — admin.
-
 	Тестирование системы аутентификации:
 	1.Тесты для регистрации.
 	2.Тесты для входа.
@@ -106,14 +136,12 @@ func main() {
 
 	Подытожим:
 	a.Реализация админских эндпоинтов для управления пользователями.
-	b.Создание middleware для проверки роли админа.
-	c.Тестирование системы аутентификации и админских операций.
-	d.Документирование API с использованием Swagger.
+	b.Тестирование системы аутентификации и админских операций.
+	c.Документирование API с использованием Swagger.
 
 */
 
 // написать тесты для сервиса auth (для регистрации и входа), поюзать mok-тесты...
-// есть проблемка с подтверждение кода, если пользователь не успел подтвердить email (2 минуты есть на это), то ему придётся заного регестрироваться
 // есть проблемка с записью данных в tokens с id, даже когда перезаписывается строка, id инкриментится
 
 //
@@ -130,5 +158,7 @@ func main() {
 // надо создать таблицу с информацией активности аккаунтов (login_history), чтобы просмотривать когда и кто заходил на аккаунт ++
 // создать таблицу в PostgreSQL (login_history) и протестировать работу ++
 // Создать окончательные таблицы в PostgreSQL (users, tokens, login_history) и протестировать работу ++
+// повторная отправка кода подтверждения, чтобы не регистрироваться заново после истечения 2 минут ++
+// создать middleware для проверки JWT токена и роли admin ++
 //
 //
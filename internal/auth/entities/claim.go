@@ -2,14 +2,28 @@ package entities
 
 import "github.com/golang-jwt/jwt/v4"
 
+// Типы токенов (claim typ): access и refresh токены подписываются одним ключом,
+// поэтому тип не позволяет использовать refresh токен вместо access токена и наоборот.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 type AccessClaim struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID        int    `json:"id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Type      string `json:"typ"`           // Тип токена (TokenTypeAccess)
+	SessionID string `json:"sid,omitempty"` // ID сессии, в рамках которой выдан токен
+	// Время выпуска в миллисекундах: iat хранит только секунды, а токен, выпущенный
+	// в ту же секунду, что и отзыв всех токенов пользователя, не должен считаться отозванным
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type RefreshClaim struct {
-	ID int `json:"id"`
+	ID        int    `json:"id"`
+	Type      string `json:"typ"`           // Тип токена (TokenTypeRefresh)
+	SessionID string `json:"sid,omitempty"` // ID сессии (устройства), к которой привязан токен
 	jwt.RegisteredClaims
 }
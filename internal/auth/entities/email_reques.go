@@ -1,5 +1,44 @@
 package entities
 
 type ConfirmEmailRequest struct {
+	Email             string `json:"email"`
+	RegistrationToken string `json:"registrationToken"` // Токен, выданный при регистрации
+	Code              string `json:"code"`
+}
+
+// ResendCodeRequest — запрос на повторную отправку кода подтверждения
+type ResendCodeRequest struct {
+	Email             string `json:"email"`
+	RegistrationToken string `json:"registrationToken"` // Токен, выданный при регистрации
+}
+
+// ConfirmPhoneRequest — запрос на подтверждение номера телефона кодом из SMS
+type ConfirmPhoneRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// PhoneCodeRequest — запрос на отправку кода для входа по номеру телефона
+type PhoneCodeRequest struct {
+	Phone string `json:"phone"`
+}
+
+// PhoneSignInRequest — вход по номеру телефона и одноразовому коду из SMS
+type PhoneSignInRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+// ChangeEmailRequest — запрос на смену email авторизованным пользователем.
+// Смена подтверждается текущим паролем, а при включённой 2FA — ещё и вторым фактором.
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"newEmail"`
+	CurrentPassword string `json:"currentPassword"`
+	Code            string `json:"code"`         // Код из приложения-аутентификатора
+	RecoveryCode    string `json:"recoveryCode"` // Код восстановления (вместо кода из приложения)
+}
+
+// ConfirmEmailChangeRequest — подтверждение нового email кодом из письма
+type ConfirmEmailChangeRequest struct {
 	Code string `json:"code"`
 }
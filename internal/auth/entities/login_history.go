@@ -0,0 +1,54 @@
+package entities
+
+import "time"
+
+// Причины неудачного входа, сохраняемые в login_history
+const (
+	LoginFailUnknownEmail  = "unknown_email"  // Пользователь с таким email не найден
+	LoginFailWrongPassword = "wrong_password" // Неверный пароль
+	LoginFailBlocked       = "blocked"        // Пользователь удалён или заблокирован
+	LoginFailLocked        = "locked"         // Вход временно запрещён из-за подбора пароля
+	LoginFailWrongMFA      = "wrong_mfa"      // Неверный код двухфакторной аутентификации
+	LoginFailWrongCode     = "wrong_code"     // Неверный код для входа по номеру телефона
+)
+
+// LoginHistory представляет запись о попытке входа (успешной или неудачной)
+type LoginHistory struct {
+	ID        int       `json:"id"`                      // Уникальный идентификатор записи
+	UserID    int       `json:"userId,omitempty"`        // ID пользователя (0, если пользователь не найден)
+	Email     string    `json:"email"`                   // Email, с которым выполнялась попытка входа
+	Phone     string    `json:"phone,omitempty"`         // Телефон, если попытка входа выполнялась по SMS
+	IPAddress string    `json:"ipAddress"`               // IP-адрес, с которого выполнена попытка
+	UserAgent string    `json:"userAgent,omitempty"`     // Заголовок User-Agent клиента
+	Device    string    `json:"device,omitempty"`        // Тип устройства (desktop, mobile, tablet, bot, unknown)
+	OS        string    `json:"os,omitempty"`            // Операционная система
+	SessionID string    `json:"sessionId,omitempty"`     // ID сессии, созданной при успешном входе
+	Success   bool      `json:"success"`                 // Признак успешного входа
+	Reason    string    `json:"failureReason,omitempty"` // Причина неудачи
+	LoginTime time.Time `json:"loginTime"`               // Дата и время попытки
+}
+
+// KnownLoginContext — встречались ли IP-адрес и устройство в успешных входах пользователя
+type KnownLoginContext struct {
+	HasHistory  bool // Есть ли у пользователя хотя бы один успешный вход
+	KnownIP     bool // Был ли успешный вход с этого IP-адреса
+	KnownDevice bool // Был ли успешный вход с этого типа устройства и ОС
+}
+
+// LoginHistoryFilter — условия выборки истории входов. Нулевые значения полей не ограничивают выборку.
+type LoginHistoryFilter struct {
+	UserID int       // ID пользователя
+	IP     string    // IP-адрес или подсеть в нотации CIDR
+	From   time.Time // Начало периода (включительно)
+	To     time.Time // Конец периода (не включительно)
+	Limit  int       // Размер страницы
+	Offset int       // Смещение от начала выборки
+}
+
+// LoginHistoryPage — страница истории входов
+type LoginHistoryPage struct {
+	Items []LoginHistory `json:"items"` // Записи, от новых к старым
+	Page  int            `json:"page"`  // Номер страницы (с 1)
+	Limit int            `json:"limit"` // Размер страницы
+	Total int            `json:"total"` // Общее количество записей
+}
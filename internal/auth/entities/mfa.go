@@ -0,0 +1,24 @@
+package entities
+
+// TOTPEnrollResponse — данные для подключения приложения-аутентификатора
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"` // Секрет в base32 для ручного ввода
+	URI    string `json:"uri"`    // otpauth URI для QR-кода
+}
+
+// TOTPVerifyRequest — запрос на подтверждение подключения 2FA кодом из приложения
+type TOTPVerifyRequest struct {
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse — одноразовые коды восстановления, показываются пользователю один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFASignInRequest — второй шаг входа: код из приложения или код восстановления
+type MFASignInRequest struct {
+	MFAToken     string `json:"mfaToken"`     // Токен MFA-челленджа, полученный на первом шаге входа
+	Code         string `json:"code"`         // Код из приложения-аутентификатора
+	RecoveryCode string `json:"recoveryCode"` // Код восстановления (вместо кода из приложения)
+}
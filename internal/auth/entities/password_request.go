@@ -0,0 +1,31 @@
+package entities
+
+// ForgotPasswordRequest — запрос на получение кода для сброса пароля
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest — запрос на установку нового пароля по коду из письма
+type ResetPasswordRequest struct {
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+// ChangePasswordRequest — запрос на смену пароля авторизованным пользователем.
+// Смена подтверждается текущим паролем, а при включённой 2FA — ещё и вторым фактором.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+	Code            string `json:"code"`         // Код из приложения-аутентификатора
+	RecoveryCode    string `json:"recoveryCode"` // Код восстановления (вместо кода из приложения)
+}
+
+// DeleteAccountRequest — запрос на удаление аккаунта, подтверждённый паролем или кодом из письма.
+// При подтверждении паролем и включённой 2FA требуется ещё и второй фактор.
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`         // Код, отправленный на email (для аккаунтов без пароля)
+	MFACode      string `json:"mfaCode"`      // Код из приложения-аутентификатора
+	RecoveryCode string `json:"recoveryCode"` // Код восстановления (вместо кода из приложения)
+}
@@ -3,3 +3,10 @@ package entities
 type Response struct {
 	Message string `json:"message"`
 }
+
+// RegisterResponse — ответ на начало регистрации. RegistrationToken нужно передать при подтверждении email
+// и повторной отправке кода: так регистрацию может завершить только тот, кто её начал.
+type RegisterResponse struct {
+	Message           string `json:"message"`
+	RegistrationToken string `json:"registrationToken"`
+}
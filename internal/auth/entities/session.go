@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// Session представляет сессию пользователя на конкретном устройстве (строка в таблице tokens)
+type Session struct {
+	ID         string    `json:"id"`         // Уникальный идентификатор сессии
+	UserID     int       `json:"-"`          // ID пользователя, которому принадлежит сессия
+	TokenHash  string    `json:"-"`          // Хеш текущего refresh токена сессии (не показывается в JSON)
+	UserAgent  string    `json:"userAgent"`  // User-Agent устройства, с которого выполнен вход
+	IPAddress  string    `json:"ipAddress"`  // IP-адрес, с которого выполнен вход
+	CreatedAt  time.Time `json:"createdAt"`  // Дата и время входа
+	LastUsedAt time.Time `json:"lastUsedAt"` // Дата и время последнего обновления токенов
+	ExpiresAt  time.Time `json:"expiresAt"`  // Дата и время истечения refresh токена
+	Current    bool      `json:"current"`    // Признак того, что запрос выполнен из этой сессии
+}
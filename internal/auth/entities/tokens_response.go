@@ -1,6 +1,8 @@
 package entities
 
 type TokensResponse struct {
-	AccessToken  string `json:"accessToken"`
+	AccessToken  string `json:"accessToken,omitempty"`
 	RefreshToken string `json:"-"`
+	MFARequired  bool   `json:"mfaRequired,omitempty"` // Для входа нужен второй фактор
+	MFAToken     string `json:"mfaToken,omitempty"`    // Короткоживущий токен MFA-челленджа
 }
@@ -12,6 +12,12 @@ type User struct {
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`   // Дата и время создания пользователя
 	Status    string    `json:"status" db:"status"`          // Статус пользователя (например: active, suspended, blocked, removed)
 	Role      string    `json:"role" db:"role"`              // Роль пользователя (например: user, admin)
+	Locale    string    `json:"locale" db:"locale"`          // Язык писем пользователя (ru, en)
+
+	PhoneVerified bool `json:"-" db:"phone_verified"` // Подтверждён ли номер телефона
+	TOTPEnabled   bool `json:"-" db:"totp_enabled"`   // Включена ли двухфакторная аутентификация
+
+	PasswordResetRequired bool `json:"-" db:"password_reset_required"` // Вход по паролю запрещён до сброса пароля
 }
 
 // NewUser создает новый объект пользователя с заданными значениями
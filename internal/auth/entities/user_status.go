@@ -0,0 +1,57 @@
+package entities
+
+import "time"
+
+// Статусы аккаунта. Статус отражает только состояние аккаунта (модерация, удаление),
+// а не то, находится ли пользователь в сети — присутствие хранится отдельно (см. Presence).
+const (
+	UserStatusPending   = "pending"   // Аккаунт создан, но ещё не активирован
+	UserStatusActive    = "active"    // Обычный рабочий аккаунт
+	UserStatusSuspended = "suspended" // Временно приостановлен администратором
+	UserStatusBlocked   = "blocked"   // Заблокирован администратором
+	UserStatusRemoved   = "removed"   // Удалён (персональные данные обезличиваются после срока ожидания)
+)
+
+// userStatusTransitions — разрешённые переходы между статусами аккаунта.
+// Удалённый аккаунт восстановить нельзя.
+var userStatusTransitions = map[string][]string{
+	UserStatusPending:   {UserStatusActive, UserStatusRemoved},
+	UserStatusActive:    {UserStatusSuspended, UserStatusBlocked, UserStatusRemoved},
+	UserStatusSuspended: {UserStatusActive, UserStatusBlocked, UserStatusRemoved},
+	UserStatusBlocked:   {UserStatusActive, UserStatusRemoved},
+	UserStatusRemoved:   {},
+}
+
+// IsValidUserStatus сообщает, является ли строка известным статусом аккаунта.
+func IsValidUserStatus(status string) bool {
+	_, ok := userStatusTransitions[status]
+	return ok
+}
+
+// CanChangeUserStatus сообщает, разрешён ли переход аккаунта из статуса from в статус to.
+func CanChangeUserStatus(from, to string) bool {
+	for _, status := range userStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanSignIn сообщает, может ли пользователь с таким статусом входить в аккаунт.
+func CanSignIn(status string) bool {
+	return status == UserStatusActive
+}
+
+// ChangeUserStatusRequest — запрос администратора на смену статуса аккаунта
+type ChangeUserStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// Presence — присутствие пользователя в сети.
+type Presence struct {
+	UserID   int        `json:"userId"`
+	Online   bool       `json:"online"`             // Был активен в последние несколько минут
+	LastSeen *time.Time `json:"lastSeen,omitempty"` // Время последней активности (если известно)
+}
@@ -1,32 +1,69 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"food-delivery/internal/auth/entities"
 	"food-delivery/internal/auth/service"
+	"food-delivery/pkg/jwtkeys"
 	"food-delivery/pkg/logger"
+	"food-delivery/pkg/mailer"
+	"food-delivery/pkg/middlewares"
 	"food-delivery/pkg/utils"
+	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type AuthHandlerInt interface {
 	Register(w http.ResponseWriter, r *http.Request)
 	ConfirmEmail(w http.ResponseWriter, r *http.Request)
+	ResendCode(w http.ResponseWriter, r *http.Request)
+	ConfirmPhone(w http.ResponseWriter, r *http.Request)
+	ResendPhoneCode(w http.ResponseWriter, r *http.Request)
 	SignIn(w http.ResponseWriter, r *http.Request)
+	RequestPhoneSignInCode(w http.ResponseWriter, r *http.Request)
+	SignInByPhone(w http.ResponseWriter, r *http.Request)
+	OIDCSignIn(w http.ResponseWriter, r *http.Request)
+	OIDCCallback(w http.ResponseWriter, r *http.Request)
 	RefreshTokens(w http.ResponseWriter, r *http.Request)
 	SignOut(w http.ResponseWriter, r *http.Request)
+	GetSessions(w http.ResponseWriter, r *http.Request)
+	DeleteSession(w http.ResponseWriter, r *http.Request)
+	SignOutAll(w http.ResponseWriter, r *http.Request)
+	NotMePage(w http.ResponseWriter, r *http.Request)
+	NotMe(w http.ResponseWriter, r *http.Request)
+	AdminSignOutUser(w http.ResponseWriter, r *http.Request)
+	GetLoginHistory(w http.ResponseWriter, r *http.Request)
+	AdminLoginHistory(w http.ResponseWriter, r *http.Request)
+	AdminChangeUserStatus(w http.ResponseWriter, r *http.Request)
+	AdminGetPresence(w http.ResponseWriter, r *http.Request)
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+	ChangePassword(w http.ResponseWriter, r *http.Request)
+	ChangeEmail(w http.ResponseWriter, r *http.Request)
+	ConfirmEmailChange(w http.ResponseWriter, r *http.Request)
+	SendDeletionCode(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+	EnrollTOTP(w http.ResponseWriter, r *http.Request)
+	VerifyTOTP(w http.ResponseWriter, r *http.Request)
+	SignInMFA(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
 }
 
 type AuthHandler struct {
 	service service.AuthServiceInt
+	cookies *CookieConfig
 	log     *logger.Logger
 }
 
-func NewAuthHandler(service service.AuthServiceInt, log *logger.Logger) *AuthHandler {
+func NewAuthHandler(service service.AuthServiceInt, cookies *CookieConfig, log *logger.Logger) *AuthHandler {
 	return &AuthHandler{
 		service: service,
+		cookies: cookies,
 		log:     log,
 	}
 }
@@ -41,8 +78,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Если язык писем не указан явно, берём его из заголовка Accept-Language.
+	if user.Locale == "" {
+		user.Locale = mailer.LocaleFromAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+
 	// Вызов сервис слоя для 1 этапа регистрации пользователя.
-	if err := h.service.Register(&user); err != nil {
+	registrationToken, err := h.service.Register(&user)
+	if err != nil {
 		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -51,8 +94,11 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
-	message := fmt.Sprintf("Письмо с кодом подтверждения отправлено на почту %s", user.Email)
-	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+	response := entities.RegisterResponse{
+		Message:           fmt.Sprintf("Письмо с кодом подтверждения отправлено на почту %s", user.Email),
+		RegistrationToken: registrationToken,
+	}
+	if err = json.NewEncoder(w).Encode(response); err != nil {
 		h.log.Error("Ошибка при отправке ответа: ", err)
 	}
 }
@@ -67,7 +113,7 @@ func (h *AuthHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверяем код подтверждения через слой сервиса.
-	if err := h.service.ConfirmEmail(request.Code); err != nil {
+	if err := h.service.ConfirmEmail(request.Email, request.RegistrationToken, request.Code); err != nil {
 		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -87,6 +133,80 @@ func (h *AuthHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *AuthHandler) ResendCode(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру ResendCodeRequest.
+	var request entities.ResendCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ResendCode: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для повторной отправки кода.
+	if err := h.service.ResendCode(request.Email, request.RegistrationToken); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Формируем и отправляем успешный ответ.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	message := fmt.Sprintf("Новый код подтверждения отправлен на почту %s", request.Email)
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ConfirmPhone(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру ConfirmPhoneRequest.
+	var request entities.ConfirmPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ConfirmPhone: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем код из SMS через слой сервиса.
+	if err := h.service.ConfirmPhone(request.Email, request.Code); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем JSON-ответ.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Номер телефона подтверждён"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ResendPhoneCode(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру ResendCodeRequest.
+	var request entities.ResendCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ResendPhoneCode: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для повторной отправки кода по SMS.
+	if err := h.service.ResendPhoneCode(request.Email, request.RegistrationToken); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Формируем и отправляем успешный ответ.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Новый код подтверждения отправлен по SMS"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
 func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	// Декодируем тело запроса в структуру User.
 	var user entities.User
@@ -96,24 +216,25 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userAddr := r.RemoteAddr
+	// IP-адрес клиента определён ClientIPMiddleware с учётом доверенных прокси
+	ipAddress := middlewares.ClientIPFromContext(r.Context())
+	userAgent := r.UserAgent()
 
 	// Вызов сервис слоя для авторизации пользователя.
-	tokens, err := h.service.SignIn(&user, userAddr)
+	tokens, err := h.service.SignIn(&user, ipAddress, userAgent)
 	if err != nil {
 		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Устанавливаем refresh токен в cookies.
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    tokens.RefreshToken,
-		Expires:  time.Now().Add(30 * 24 * time.Hour), // Время жизни куки (30 дней)
-		HttpOnly: false,                               // Защита от доступа через JavaScript
-		Secure:   false,                               // Для использования в HTTP (для HTTPS изменить на true)
-		Path:     "/",                                 // Путь для куки
-	})
+	// Устанавливаем refresh токен в cookies (если нужен второй фактор, токенов ещё нет).
+	if tokens.RefreshToken != "" {
+		if err := h.cookies.setRefreshCookie(w, tokens.RefreshToken); err != nil {
+			h.log.Error("Ошибка генерации CSRF токена: ", err)
+			utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Устанавливаем заголовки ответа.
 	w.Header().Set("Content-Type", "application/json")
@@ -128,9 +249,136 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *AuthHandler) RequestPhoneSignInCode(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру PhoneCodeRequest.
+	var request entities.PhoneCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в PhoneCodeRequest: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Отправляем код для входа через слой сервиса.
+	if err := h.service.RequestPhoneSignInCode(request.Phone); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Ответ одинаков для зарегистрированных и незарегистрированных номеров.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Если номер зарегистрирован и подтверждён, на него отправлен код для входа"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) SignInByPhone(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру PhoneSignInRequest.
+	var request entities.PhoneSignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в PhoneSignInRequest: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для входа по номеру телефона.
+	tokens, err := h.service.SignInByPhone(&request, middlewares.ClientIPFromContext(r.Context()), r.UserAgent())
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем refresh токен в cookies (если нужен второй фактор, токенов ещё нет).
+	if tokens.RefreshToken != "" {
+		if err := h.cookies.setRefreshCookie(w, tokens.RefreshToken); err != nil {
+			h.log.Error("Ошибка генерации CSRF токена: ", err)
+			utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем данные с access токеном в JSON-формате.
+	if err = json.NewEncoder(w).Encode(&tokens); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+		utils.DecodeErr(w, "Ошибка отправки данных", http.StatusInternalServerError)
+	}
+}
+
+func (h *AuthHandler) OIDCSignIn(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	// Начинаем вход через провайдера через слой сервиса.
+	authURL, state, err := h.service.StartOIDCSignIn(provider)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// state сохраняется в cookie, чтобы callback был принят только в том браузере, где начат вход.
+	h.cookies.setOIDCStateCookie(w, state, 10*time.Minute)
+
+	// Перенаправляем пользователя на страницу входа провайдера.
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	query := r.URL.Query()
+
+	// Провайдер вернул ошибку (например, пользователь отказался от входа).
+	if errParam := query.Get("error"); errParam != "" {
+		h.log.Error(fmt.Sprintf("провайдер %s вернул ошибку:", provider), errors.New(errParam))
+		utils.DecodeErr(w, "вход через провайдера отменён", http.StatusBadRequest)
+		return
+	}
+
+	// state из запроса должен совпадать со state из cookie.
+	cookie, err := r.Cookie(oidcStateCookie)
+	state := query.Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		utils.DecodeErr(w, "неверный параметр state", http.StatusBadRequest)
+		return
+	}
+
+	// Cookie со state больше не нужна.
+	h.cookies.clearOIDCStateCookie(w)
+
+	// Завершаем вход через слой сервиса.
+	tokens, err := h.service.FinishOIDCSignIn(provider, query.Get("code"), state, middlewares.ClientIPFromContext(r.Context()), r.UserAgent())
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем refresh токен в cookies (если нужен второй фактор, токенов ещё нет).
+	if tokens.RefreshToken != "" {
+		if err := h.cookies.setRefreshCookie(w, tokens.RefreshToken); err != nil {
+			h.log.Error("Ошибка генерации CSRF токена: ", err)
+			utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем данные с access токеном в JSON-формате.
+	if err = json.NewEncoder(w).Encode(&tokens); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+		utils.DecodeErr(w, "Ошибка отправки данных", http.StatusInternalServerError)
+	}
+}
+
 func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 	// Получаем refresh токен из cookie
-	cookie, err := r.Cookie("refresh_token")
+	cookie, err := r.Cookie(refreshCookieName)
 	if err != nil {
 		h.log.Error("не удалось получить данные из cookie:", err)
 		utils.DecodeErr(w, "ошибка авторизации", http.StatusBadRequest)
@@ -145,14 +393,11 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Устанавливаем refresh токен в cookies
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    tokens.RefreshToken,
-		Expires:  time.Now().Add(30 * 24 * time.Hour), // Время жизни куки (30 дней)
-		HttpOnly: false,                               // Защита от доступа через JavaScript
-		Secure:   false,                               // Для использования в HTTP (для HTTPS изменить на true)
-		Path:     "/",                                 // Путь для куки
-	})
+	if err := h.cookies.setRefreshCookie(w, tokens.RefreshToken); err != nil {
+		h.log.Error("Ошибка генерации CSRF токена: ", err)
+		utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+		return
+	}
 
 	// Устанавливаем заголовки ответа
 	w.Header().Set("Content-Type", "application/json")
@@ -169,27 +414,23 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) SignOut(w http.ResponseWriter, r *http.Request) {
 	// Получаем refresh токен из cookie
-	cookie, err := r.Cookie("refresh_token")
+	cookie, err := r.Cookie(refreshCookieName)
 	if err != nil {
 		h.log.Error("не удалось получить данные из cookie:", err)
 		utils.DecodeErr(w, "ошибка авторизации", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.SignOut(cookie.Value); err != nil {
+	// Access токен (если передан) отзывается вместе с сессией
+	accessToken := middlewares.BearerToken(r)
+
+	if err := h.service.SignOut(cookie.Value, accessToken); err != nil {
 		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Удаляем refresh токен в cookies
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Expires:  time.Unix(0, 0), // Устанавливаем время истечения в прошлое, чтобы удалить куку
-		HttpOnly: false,           // Защита от доступа через JavaScript
-		Secure:   false,           // Для использования в HTTP (для HTTPS изменить на true)
-		Path:     "/",             // Путь для куки
-	})
+	// Удаляем refresh токен и CSRF токен из cookies
+	h.cookies.clearRefreshCookie(w)
 
 	// Устанавливаем заголовки ответа.
 	w.Header().Set("Content-Type", "application/json")
@@ -201,3 +442,592 @@ func (h *AuthHandler) SignOut(w http.ResponseWriter, r *http.Request) {
 		h.log.Error("Ошибка при отправке ответа: ", err)
 	}
 }
+
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Вызов сервис слоя для получения списка сессий
+	sessions, err := h.service.GetSessions(claim)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем список сессий в JSON-формате.
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// ID завершаемой сессии берём из пути запроса
+	sessionID := mux.Vars(r)["id"]
+
+	if err := h.service.DeleteSession(claim, sessionID); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Сессия завершена"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) SignOutAll(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.SignOutAll(claim); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Удаляем refresh токен и CSRF токен из cookies
+	h.cookies.clearRefreshCookie(w)
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Выполнен выход на всех устройствах"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// AdminSignOutUser завершает все сессии указанного пользователя (доступно только администраторам).
+func (h *AuthHandler) AdminSignOutUser(w http.ResponseWriter, r *http.Request) {
+	// ID пользователя берём из пути запроса
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.DecodeErr(w, "неверный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.TerminateUserSessions(userID); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Все сессии пользователя завершены"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// AdminChangeUserStatus меняет статус аккаунта пользователя (доступно только администраторам).
+// Допустимые статусы: pending, active, suspended, blocked, removed; переходы ограничены правилами жизненного цикла.
+func (h *AuthHandler) AdminChangeUserStatus(w http.ResponseWriter, r *http.Request) {
+	// ID пользователя берём из пути запроса
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.DecodeErr(w, "неверный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	var request entities.ChangeUserStatusRequest
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.service.ChangeUserStatus(userID, request.Status); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Статус пользователя изменён"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// AdminGetPresence возвращает, в сети ли пользователь, и время его последней активности
+// (доступно только администраторам).
+func (h *AuthHandler) AdminGetPresence(w http.ResponseWriter, r *http.Request) {
+	// ID пользователя берём из пути запроса
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.DecodeErr(w, "неверный ID пользователя", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.GetPresence(userID)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Параметры пагинации: ?page=1&limit=20
+	page, limit, err := pageParams(r)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.GetLoginHistory(claim, page, limit)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем страницу истории входов в JSON-формате.
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// AdminLoginHistory возвращает историю входов всех пользователей (доступно только администраторам).
+// Фильтры: ?user_id=1&ip=10.0.0.0/8&from=2025-01-01&to=2025-01-31, а также page и limit.
+func (h *AuthHandler) AdminLoginHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter entities.LoginHistoryFilter
+	var err error
+
+	if value := query.Get("user_id"); value != "" {
+		if filter.UserID, err = strconv.Atoi(value); err != nil {
+			utils.DecodeErr(w, "неверный ID пользователя", http.StatusBadRequest)
+			return
+		}
+	}
+	filter.IP = query.Get("ip")
+
+	if filter.From, err = dateParam(query.Get("from"), false); err != nil {
+		utils.DecodeErr(w, "неверный формат параметра from", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = dateParam(query.Get("to"), true); err != nil {
+		utils.DecodeErr(w, "неверный формат параметра to", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := pageParams(r)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.SearchLoginHistory(&filter, page, limit)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем страницу истории входов в JSON-формате.
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру ForgotPasswordRequest.
+	var request entities.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ForgotPassword: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для отправки кода сброса пароля.
+	if err := h.service.ForgotPassword(request.Email); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Ответ не зависит от того, зарегистрирован ли email.
+	message := fmt.Sprintf("Если аккаунт с email %s существует, на него отправлен код для сброса пароля", request.Email)
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру ResetPasswordRequest.
+	var request entities.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ResetPassword: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для установки нового пароля.
+	if err := h.service.ResetPassword(&request); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	message := "Пароль изменён, выполните вход с новым паролем"
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Декодируем тело запроса в структуру ChangePasswordRequest.
+	var request entities.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ChangePassword: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для смены пароля.
+	if err := h.service.ChangePassword(claim, &request); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	message := "Пароль изменён, остальные сессии завершены"
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Декодируем тело запроса в структуру ChangeEmailRequest.
+	var request entities.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ChangeEmail: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для отправки кода на новый email.
+	if err := h.service.ChangeEmail(claim, &request); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	message := fmt.Sprintf("Код подтверждения отправлен на %s", request.NewEmail)
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: message}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Декодируем тело запроса в структуру ConfirmEmailChangeRequest.
+	var request entities.ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в ConfirmEmailChange: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для подтверждения нового email.
+	if err := h.service.ConfirmEmailChange(claim, request.Code); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Email изменён"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) SendDeletionCode(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Вызов сервис слоя для отправки кода удаления аккаунта.
+	if err := h.service.SendDeletionCode(claim); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Код подтверждения удаления отправлен на email"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Декодируем тело запроса в структуру DeleteAccountRequest.
+	var request entities.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в DeleteAccount: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для удаления аккаунта.
+	if err := h.service.DeleteAccount(claim, &request); err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Удаляем refresh токен и CSRF токен из cookies
+	h.cookies.clearRefreshCookie(w)
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем ответ с сообщением.
+	if err := json.NewEncoder(w).Encode(entities.Response{Message: "Аккаунт удалён"}); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Вызов сервис слоя для генерации секрета TOTP.
+	enrollment, err := h.service.EnrollTOTP(claim)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем секрет и otpauth URI в JSON-формате.
+	if err := json.NewEncoder(w).Encode(enrollment); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	// Пользователь и сессия берутся из access токена, проверенного AuthMiddleware
+	claim, ok := middlewares.ClaimFromContext(r.Context())
+	if !ok {
+		utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+		return
+	}
+
+	// Декодируем тело запроса в структуру TOTPVerifyRequest.
+	var request entities.TOTPVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в VerifyTOTP: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для включения 2FA.
+	recoveryCodes, err := h.service.VerifyTOTP(claim, request.Code)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем коды восстановления в JSON-формате (показываются один раз).
+	if err := json.NewEncoder(w).Encode(recoveryCodes); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+func (h *AuthHandler) SignInMFA(w http.ResponseWriter, r *http.Request) {
+	// Декодируем тело запроса в структуру MFASignInRequest.
+	var request entities.MFASignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Ошибка декодирования JSON в SignInMFA: ", err)
+		utils.DecodeErr(w, "Неверный формат данных", http.StatusBadRequest)
+		return
+	}
+
+	// Вызов сервис слоя для проверки второго фактора.
+	tokens, err := h.service.SignInMFA(&request)
+	if err != nil {
+		utils.DecodeErr(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Устанавливаем refresh токен в cookies.
+	if err := h.cookies.setRefreshCookie(w, tokens.RefreshToken); err != nil {
+		h.log.Error("Ошибка генерации CSRF токена: ", err)
+		utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+		return
+	}
+
+	// Устанавливаем заголовки ответа.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// Отправляем данные с access токеном в JSON-формате.
+	if err := json.NewEncoder(w).Encode(&tokens); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// JWKS отдаёт открытые ключи подписи токенов, чтобы другие модули могли проверять access токены без секрета.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Ключи меняются редко, но после ротации новый kid должен подхватываться быстро
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(jwtkeys.Default().JWKS()); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
+
+// pageParams читает параметры пагинации page и limit из строки запроса (0, если параметр не указан).
+// Верхнюю границу номера страницы проверяет сервис.
+func pageParams(r *http.Request) (int, int, error) {
+	var page, limit int
+	var err error
+
+	if value := r.URL.Query().Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			return 0, 0, errors.New("неверный номер страницы")
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			return 0, 0, errors.New("неверный размер страницы")
+		}
+	}
+
+	return page, limit, nil
+}
+
+// dateParam разбирает дату в формате RFC 3339 или YYYY-MM-DD. Для конца периода (endOfDay)
+// дата без времени означает весь указанный день включительно.
+func dateParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24 * time.Hour)
+	}
+
+	return t, nil
+}
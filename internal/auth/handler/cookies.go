@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery/pkg/middlewares"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	refreshCookieName = "refresh_token"
+	refreshCookieTTL  = 30 * 24 * time.Hour // Совпадает со временем жизни refresh токена
+	oidcStateCookie   = "oidc_state"
+)
+
+// CookieConfig — настройки cookie модуля auth.
+type CookieConfig struct {
+	Domain   string        // Домен cookie (пустой — только текущий хост)
+	Path     string        // Путь refresh cookie: она отправляется только на эндпоинты auth
+	Secure   bool          // Отправлять cookie только по HTTPS
+	SameSite http.SameSite // Политика SameSite для refresh и CSRF cookie
+}
+
+// LoadCookieConfig читает настройки cookie из переменных окружения: COOKIE_DOMAIN, COOKIE_PATH (по умолчанию /api/auth),
+// COOKIE_SECURE (по умолчанию true) и COOKIE_SAMESITE (strict, lax или none, по умолчанию strict).
+func LoadCookieConfig() (*CookieConfig, error) {
+	cfg := &CookieConfig{
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		Path:     os.Getenv("COOKIE_PATH"),
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/api/auth"
+	}
+
+	if value := os.Getenv("COOKIE_SECURE"); value != "" {
+		secure, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное значение COOKIE_SECURE: %q", value)
+		}
+		cfg.Secure = secure
+	}
+
+	switch value := strings.ToLower(os.Getenv("COOKIE_SAMESITE")); value {
+	case "", "strict":
+		cfg.SameSite = http.SameSiteStrictMode
+	case "lax":
+		cfg.SameSite = http.SameSiteLaxMode
+	case "none":
+		cfg.SameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("некорректное значение COOKIE_SAMESITE: %q", value)
+	}
+
+	// Браузеры отклоняют cookie с SameSite=None без Secure
+	if cfg.SameSite == http.SameSiteNoneMode && !cfg.Secure {
+		return nil, errors.New("COOKIE_SAMESITE=none требует COOKIE_SECURE=true")
+	}
+
+	return cfg, nil
+}
+
+// setRefreshCookie устанавливает refresh токен в cookies вместе с новым CSRF токеном.
+// CSRF токен также возвращается в заголовке X-CSRF-Token, чтобы клиенту не нужно было читать cookie.
+func (c *CookieConfig) setRefreshCookie(w http.ResponseWriter, refreshToken string) error {
+	csrfToken, err := middlewares.NewCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    refreshToken,
+		MaxAge:   int(refreshCookieTTL / time.Second),
+		Domain:   c.Domain,
+		Path:     c.Path,
+		HttpOnly: true, // Refresh токен недоступен JavaScript
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+	})
+
+	// CSRF cookie должна читаться JavaScript на любой странице приложения, поэтому её путь — корень
+	http.SetCookie(w, &http.Cookie{
+		Name:     middlewares.CSRFCookieName,
+		Value:    csrfToken,
+		MaxAge:   int(refreshCookieTTL / time.Second),
+		Domain:   c.Domain,
+		Path:     "/",
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+	})
+	w.Header().Set(middlewares.CSRFHeaderName, csrfToken)
+
+	c.clearLegacyRefreshCookie(w)
+
+	return nil
+}
+
+// clearLegacyRefreshCookie удаляет refresh cookie с путём "/", которую выдавали до введения COOKIE_PATH.
+// Иначе браузер продолжал бы отправлять её вместе с новой cookie.
+func (c *CookieConfig) clearLegacyRefreshCookie(w http.ResponseWriter) {
+	if c.Path == "/" {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   refreshCookieName,
+		Value:  "",
+		MaxAge: -1,
+		Path:   "/",
+	})
+}
+
+// clearRefreshCookie удаляет refresh токен и CSRF токен из cookies.
+func (c *CookieConfig) clearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		HttpOnly: true,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     middlewares.CSRFCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Domain:   c.Domain,
+		Path:     "/",
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+	})
+	c.clearLegacyRefreshCookie(w)
+}
+
+// setOIDCStateCookie сохраняет state входа через провайдера. SameSite=Lax обязателен:
+// cookie должна прийти при возврате пользователя от провайдера. Путь — корень, чтобы cookie дошла
+// до callback независимо от COOKIE_PATH.
+func (c *CookieConfig) setOIDCStateCookie(w http.ResponseWriter, state string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		MaxAge:   int(ttl / time.Second),
+		Domain:   c.Domain,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearOIDCStateCookie удаляет cookie со state входа через провайдера.
+func (c *CookieConfig) clearOIDCStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		MaxAge:   -1,
+		Domain:   c.Domain,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
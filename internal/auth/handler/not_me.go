@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// notMePage — страница для ссылки «это был не я» из письма о новом входе. GET только показывает страницу
+// с кнопкой подтверждения: сканеры ссылок в почте переходят по ссылкам, и действие по GET
+// завершало бы сессии без ведома пользователя. Сами сессии завершает POST той же формы.
+var notMePage = template.Must(template.New("not-me").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>Вход в аккаунт</title>
+</head>
+<body style="margin:0;padding:24px;background:#f4f4f5;font-family:Arial,Helvetica,sans-serif;color:#18181b;">
+<div style="max-width:560px;margin:0 auto;background:#ffffff;border-radius:8px;padding:32px;font-size:15px;line-height:1.5;">
+{{if .Token}}
+<h1 style="font-size:20px;margin:0 0 16px;">Это были не вы?</h1>
+<p>Если вы не входили в аккаунт, завершите все сеансы. Вход по паролю будет заблокирован до сброса пароля,
+а код для сброса придёт на ваш email.</p>
+<form method="post" action="not-me">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit" style="padding:12px 20px;font-size:15px;border:0;border-radius:6px;background:#dc2626;color:#ffffff;cursor:pointer;">Завершить все сеансы</button>
+</form>
+<p style="color:#71717a;">Если это были вы, просто закройте страницу.</p>
+{{else if .Error}}
+<h1 style="font-size:20px;margin:0 0 16px;">Не удалось завершить сеансы</h1>
+<p>{{.Error}}</p>
+{{else}}
+<h1 style="font-size:20px;margin:0 0 16px;">Все сеансы завершены</h1>
+<p>Код для сброса пароля отправлен на ваш email. Войти по паролю можно будет после его сброса.</p>
+{{end}}
+</div>
+</body>
+</html>
+`))
+
+// notMePageData — данные страницы: Token для формы подтверждения, Error или пустые поля для результата.
+type notMePageData struct {
+	Token string
+	Error string
+}
+
+// NotMePage показывает страницу подтверждения для ссылки «это был не я». Состояние не меняется.
+func (h *AuthHandler) NotMePage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.renderNotMePage(w, http.StatusBadRequest, notMePageData{Error: "Ссылка недействительна"})
+		return
+	}
+
+	h.renderNotMePage(w, http.StatusOK, notMePageData{Token: token})
+}
+
+// NotMe завершает все сессии по токену из формы страницы «это был не я».
+func (h *AuthHandler) NotMe(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.NotMe(r.PostFormValue("token")); err != nil {
+		h.renderNotMePage(w, http.StatusBadRequest, notMePageData{Error: err.Error()})
+		return
+	}
+
+	h.renderNotMePage(w, http.StatusOK, notMePageData{})
+}
+
+func (h *AuthHandler) renderNotMePage(w http.ResponseWriter, status int, data notMePageData) {
+	// Токен из ссылки не должен попасть в кеш, в Referer или на страницу в чужом фрейме
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+
+	if err := notMePage.Execute(w, data); err != nil {
+		h.log.Error("Ошибка при отправке ответа: ", err)
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// MarkUserRemoved переводит пользователя в статус removed и запоминает время удаления.
+// Для уже удалённого пользователя время удаления (и срок обезличивания) не меняется.
+func (r *AuthRepository) MarkUserRemoved(userID int) error {
+	query := `UPDATE users SET status = 'removed', removed_at = NOW() WHERE id = $1 AND status <> 'removed'`
+
+	if _, err := r.db.Exec(query, userID); err != nil {
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// ChangeUserStatus переводит пользователя из статуса from в статус to. Если статус успел измениться
+// (или пользователь не найден), возвращает ErrStatusChanged.
+func (r *AuthRepository) ChangeUserStatus(userID int, from, to string) error {
+	query := `UPDATE users SET status = $1 WHERE id = $2 AND status = $3`
+
+	result, err := r.db.Exec(query, to, userID, from)
+	if err != nil {
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrStatusChanged
+	}
+
+	return nil
+}
+
+// AnonymizeRemovedUsers обезличивает пользователей, удалённых не позже removedBefore: имя, email и телефон
+// заменяются заглушками, удаляются секреты, коды восстановления, привязки к провайдерам и персональные
+// данные в истории входов. Строка users с её ID сохраняется, поэтому заказы и платежи продолжают
+// ссылаться на пользователя, но уже псевдонимно. Возвращает количество обезличенных пользователей.
+func (r *AuthRepository) AnonymizeRemovedUsers(removedBefore time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Ошибка при открытии транзакции:", err)
+		return 0, errInternal
+	}
+	defer tx.Rollback()
+
+	// Обезличивание запускается на каждом экземпляре приложения: строки, которые уже обрабатывает
+	// другой экземпляр, заблокированы его транзакцией и пропускаются (FOR UPDATE SKIP LOCKED).
+	// Email остаётся уникальным и не принадлежит реальному домену (RFC 2606)
+	rows, err := tx.Query(`
+		UPDATE users
+		SET firstname = 'Удалённый пользователь',
+		    email = 'deleted-' || id || '@deleted.invalid',
+		    phone = NULL,
+		    phone_raw = NULL,
+		    phone_verified = FALSE,
+		    password_hash = '',
+		    totp_secret = NULL,
+		    totp_enabled = FALSE,
+		    anonymized_at = NOW()
+		WHERE id IN (
+			SELECT id FROM users
+			WHERE status = 'removed' AND removed_at <= $1 AND anonymized_at IS NULL
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`, removedBefore)
+	if err != nil {
+		r.log.Error("Ошибка при обезличивании пользователей:", err)
+		return 0, errInternal
+	}
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			r.log.Error("Ошибка при чтении данных из DB:", err)
+			return 0, errInternal
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		r.log.Error("Ошибка при чтении данных из DB:", err)
+		return 0, errInternal
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	cleanup := []string{
+		`DELETE FROM mfa_recovery_codes WHERE user_id = ANY($1)`,
+		`DELETE FROM user_identities WHERE user_id = ANY($1)`,
+		`UPDATE login_history
+		 SET email = NULL, phone = NULL, ip_address = NULL, user_agent = NULL, device = NULL, os = NULL, session_id = NULL
+		 WHERE user_id = ANY($1)`,
+	}
+	for _, query := range cleanup {
+		if _, err = tx.Exec(query, pq.Array(userIDs)); err != nil {
+			r.log.Error("Ошибка при обезличивании пользователей:", err)
+			return 0, errInternal
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Ошибка при подтверждении транзакции:", err)
+		return 0, errInternal
+	}
+
+	return len(userIDs), nil
+}
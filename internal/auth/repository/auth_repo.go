@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"food-delivery/internal/auth/entities"
 	"food-delivery/pkg/logger"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/lib/pq"
 	"strings"
 	"time"
 )
@@ -16,18 +16,47 @@ var (
 	errNoRows   = errors.New("пользователь с таким email не существует")
 	errEmail    = errors.New("пользователь с таким email уже существует")
 	errPhone    = errors.New("пользователь с таким номером телефона уже существует")
+
+	ErrSessionNotFound = errors.New("сессия не найдена или уже завершена")
+	ErrStatusChanged   = errors.New("статус пользователя изменился, повторите запрос")
 )
 
+// phoneVerifiedConstraint — уникальный индекс по подтверждённым номерам телефона.
+const phoneVerifiedConstraint = "users_phone_verified_key"
+
 type AuthRepoInt interface {
 	TestData(user *entities.User) error
 	SaveUser(user *entities.User) error
 	DBVerifyUser(user *entities.User) (*entities.User, error)
-	PersistToken(userID int, refreshToken string, expiresAt time.Time) error
+	CreateSession(session *entities.Session) error
+	RotateToken(sessionID, oldTokenHash, newTokenHash string, expiresAt time.Time) error
 	GetUserByID(id int) (*entities.User, error)
-	GetTokenExpiryTime(userID int) (*entities.RefreshClaim, error)
+	GetUserByEmail(email string) (*entities.User, error)
+	GetUserByPhone(phone string) (*entities.User, error)
+	GetSession(sessionID string) (*entities.Session, error)
+	GetSessionByTokenHash(tokenHash string) (*entities.Session, error)
+	GetSessionsByUserID(userID int) ([]entities.Session, error)
+	DeleteSession(userID int, sessionID string) error
+	DeleteOtherSessions(userID int, keepSessionID string) error
+	GetPasswordHash(userID int) (string, error)
+	UpgradePasswordHash(userID int, oldHash, newHash string) error
+	UpdateEmail(userID int, email string) error
+	VerifyPhone(userID int) error
 	DeleteTokenByID(userID int) error
 	UpdateRecord(table string, fields map[string]interface{}, id int) error
-	SaveLoginHistory(userID int, ipAddress string) error
+	SaveLoginHistory(entry *entities.LoginHistory) error
+	GetLoginHistory(filter *entities.LoginHistoryFilter) ([]entities.LoginHistory, int, error)
+	GetKnownLoginContext(userID int, ipAddress, device, os string) (*entities.KnownLoginContext, error)
+	GetTOTPSecret(userID int) (string, error)
+	SetTOTPSecret(userID int, secret string) error
+	EnableTOTP(userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	GetUserIDByIdentity(provider, subject string) (int, error)
+	CreateIdentity(userID int, provider, subject, email string) error
+	CreateUserWithIdentity(user *entities.User, provider, subject string) (int, error)
+	MarkUserRemoved(userID int) error
+	ChangeUserStatus(userID int, from, to string) error
+	AnonymizeRemovedUsers(removedBefore time.Time) (int, error)
 }
 
 type AuthRepository struct {
@@ -45,7 +74,9 @@ func NewAuthRepository(db *sql.DB, log *logger.Logger) *AuthRepository {
 func (r *AuthRepository) TestData(user *entities.User) error {
 	var temp int
 
-	query := `SELECT 1 FROM users WHERE phone = $1 OR email = $2`
+	// Номер телефона занят только после подтверждения: неподтверждённый номер, указанный в чужом аккаунте,
+	// не мешает зарегистрироваться его настоящему владельцу.
+	query := `SELECT 1 FROM users WHERE (phone = $1 AND phone_verified) OR lower(email) = lower($2)`
 
 	// Выполняем запрос и пытаемся считать результат
 	if err := r.db.QueryRow(query, user.Phone, user.Email).Scan(&temp); err != nil {
@@ -65,16 +96,16 @@ func (r *AuthRepository) TestData(user *entities.User) error {
 func (r *AuthRepository) checkConflict(user *entities.User) error {
 	var temp int
 
-	// Запрос для проверки наличия пользователя с указанным email.
-	queryEmail := `SELECT 1 FROM users WHERE email = $1`
+	// Запрос для проверки наличия пользователя с указанным email (без учёта регистра).
+	queryEmail := `SELECT 1 FROM users WHERE lower(email) = lower($1)`
 	err := r.db.QueryRow(queryEmail, user.Email).Scan(&temp)
 	if err == nil {
 		r.log.Error("Ошибка при получении данных из DB:", err)
 		return errEmail
 	}
 
-	// Запрос для проверки наличия пользователя с указанным phone.
-	queryPhone := `SELECT 1 FROM users WHERE phone = $1`
+	// Запрос для проверки наличия пользователя с указанным подтверждённым phone.
+	queryPhone := `SELECT 1 FROM users WHERE phone = $1 AND phone_verified`
 	err = r.db.QueryRow(queryPhone, user.Phone).Scan(&temp)
 	if err == nil {
 		r.log.Error("Ошибка при получении данных из DB:", err)
@@ -85,11 +116,19 @@ func (r *AuthRepository) checkConflict(user *entities.User) error {
 }
 
 func (r *AuthRepository) SaveUser(user *entities.User) error {
-	query := `INSERT INTO users (firstname, email, password_hash, phone) VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO users (firstname, email, password_hash, phone, phone_verified, locale) VALUES ($1, $2, $3, $4, $5, $6)`
 
 	// Выполняем запрос с параметрами.
-	_, err := r.db.Exec(query, user.Firstname, user.Email, user.Password, user.Phone)
+	_, err := r.db.Exec(query, user.Firstname, user.Email, user.Password, user.Phone, user.PhoneVerified, user.Locale)
 	if err != nil {
+		// Email или подтверждённый номер успели занять после проверки TestData
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			if pqErr.Constraint == phoneVerifiedConstraint {
+				return errPhone
+			}
+			return errEmail
+		}
 		r.log.Error("Ошибка при сохранении пользователя: ", err)
 		return errInternal
 	}
@@ -102,9 +141,11 @@ func (r *AuthRepository) DBVerifyUser(user *entities.User) (*entities.User, erro
 	var resUser entities.User
 
 	// Выполняем запрос с email из переданного пользователя
-	query := `SELECT id, password_hash, status, role FROM users WHERE email = $1`
+	query := `SELECT id, password_hash, status, role, totp_enabled, password_reset_required, locale FROM users
+		WHERE lower(email) = lower($1) ORDER BY email = $1 DESC, id LIMIT 1`
 
-	err := r.db.QueryRow(query, user.Email).Scan(&resUser.ID, &resUser.Password, &resUser.Status, &resUser.Role)
+	err := r.db.QueryRow(query, user.Email).Scan(&resUser.ID, &resUser.Password, &resUser.Status, &resUser.Role,
+		&resUser.TOTPEnabled, &resUser.PasswordResetRequired, &resUser.Locale)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, err
@@ -116,36 +157,48 @@ func (r *AuthRepository) DBVerifyUser(user *entities.User) (*entities.User, erro
 	return &resUser, nil
 }
 
-func (r *AuthRepository) PersistToken(userID int, refreshToken string, expiresAt time.Time) error {
-	// SQL-запрос для вставки данных о токене в таблицу tokens или обновления, если запись уже существует
+// CreateSession создаёт новую сессию (устройство) пользователя вместе с её refresh токеном.
+func (r *AuthRepository) CreateSession(session *entities.Session) error {
 	query := `
-		INSERT INTO tokens (user_id, token, expires_at, created_at) 
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id) 
-		DO UPDATE SET 
-			token = $2, 
-			expires_at = $3, 
-			created_at = $4;
+		INSERT INTO tokens (user_id, session_id, token_hash, user_agent, ip_address, expires_at, created_at, last_used_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::inet, $6, $7, $7)
 	`
 
-	// Выполняем запрос с передачей параметров: userID, refreshToken, expiresAt и текущего времени (created_at).
-	_, err := r.db.Exec(query, userID, refreshToken, expiresAt, time.Now())
+	// Время создания и последнего использования сессии совпадают.
+	_, err := r.db.Exec(query, session.UserID, session.ID, session.TokenHash, session.UserAgent, session.IPAddress,
+		session.ExpiresAt, time.Now())
 	if err != nil {
-		r.log.Error("Ошибка при сохранении токена в базу данных:", err)
+		r.log.Error("Ошибка при сохранении сессии в базу данных:", err)
 		return errInternal
 	}
 
-	// Если все прошло успешно, возвращаем nil.
 	return nil
 }
 
+// RotateToken заменяет хеш refresh токена сессии на новый, "потребляя" предъявленный токен.
+// Замена выполняется только если oldTokenHash всё ещё является хешем текущего токена сессии, поэтому
+// из двух одновременных запросов с одним и тем же токеном успешным будет только один.
+func (r *AuthRepository) RotateToken(sessionID, oldTokenHash, newTokenHash string, expiresAt time.Time) error {
+	query := `UPDATE tokens SET token_hash = $3, expires_at = $4, last_used_at = $5 WHERE session_id = $1 AND token_hash = $2`
+
+	// Выполняем запрос с передачей параметров: sessionID, хеши старого и нового токенов, expiresAt и текущее время (last_used_at).
+	res, err := r.db.Exec(query, sessionID, oldTokenHash, newTokenHash, expiresAt, time.Now())
+	if err != nil {
+		r.log.Error("Ошибка при сохранении токена в базу данных:", err)
+		return errInternal
+	}
+
+	return r.checkAffected(res)
+}
+
 func (r *AuthRepository) GetUserByID(userID int) (*entities.User, error) {
 	var user entities.User
 
-	query := `SELECT id, email, role FROM users WHERE id = $1`
+	query := `SELECT id, firstname, email, COALESCE(phone, ''), status, role, totp_enabled, locale FROM users WHERE id = $1`
 
 	// Выполняем запрос в базу данных
-	err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Email, &user.Role)
+	err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Firstname, &user.Email, &user.Phone, &user.Status,
+		&user.Role, &user.TOTPEnabled, &user.Locale)
 	if err != nil {
 		r.log.Error("Ошибка при получении данных из DB:", err)
 		return nil, errors.New("пользователь не найден")
@@ -154,6 +207,82 @@ func (r *AuthRepository) GetUserByID(userID int) (*entities.User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail возвращает данные пользователя по email без учёта регистра (User@x.com и user@x.com —
+// один адрес). Если адрес с разным регистром указан у нескольких пользователей, предпочитается точное
+// совпадение, затем самый ранний пользователь. Если пользователь не найден, возвращается sql.ErrNoRows.
+func (r *AuthRepository) GetUserByEmail(email string) (*entities.User, error) {
+	var user entities.User
+
+	query := `SELECT id, firstname, email, COALESCE(phone, ''), status, role, locale FROM users
+		WHERE lower(email) = lower($1) ORDER BY email = $1 DESC, id LIMIT 1`
+
+	// Выполняем запрос в базу данных
+	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Firstname, &user.Email, &user.Phone, &user.Status, &user.Role,
+		&user.Locale)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, errInternal
+	}
+
+	return &user, nil
+}
+
+// GetUserByPhone возвращает данные пользователя, подтвердившего номер телефона в формате E.164.
+// Неподтверждённый номер может быть указан у нескольких пользователей, такие записи не учитываются.
+// Если пользователь не найден, возвращается sql.ErrNoRows.
+func (r *AuthRepository) GetUserByPhone(phone string) (*entities.User, error) {
+	var user entities.User
+
+	query := `SELECT id, firstname, email, phone, status, role, phone_verified, totp_enabled, locale FROM users
+		WHERE phone = $1 AND phone_verified`
+
+	// Выполняем запрос в базу данных
+	err := r.db.QueryRow(query, phone).Scan(&user.ID, &user.Firstname, &user.Email, &user.Phone, &user.Status, &user.Role,
+		&user.PhoneVerified, &user.TOTPEnabled, &user.Locale)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, errInternal
+	}
+
+	return &user, nil
+}
+
+// GetPasswordHash возвращает хеш пароля пользователя по его ID.
+func (r *AuthRepository) GetPasswordHash(userID int) (string, error) {
+	var passwordHash string
+
+	query := `SELECT password_hash FROM users WHERE id = $1`
+
+	// Выполняем запрос в базу данных
+	if err := r.db.QueryRow(query, userID).Scan(&passwordHash); err != nil {
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return "", errors.New("пользователь не найден")
+	}
+
+	return passwordHash, nil
+}
+
+// UpgradePasswordHash заменяет хеш пароля пересчитанным, только если пароль не был изменён
+// с момента чтения старого хеша.
+func (r *AuthRepository) UpgradePasswordHash(userID int, oldHash, newHash string) error {
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`
+
+	// Выполняем запрос в базу данных
+	if _, err := r.db.Exec(query, newHash, userID, oldHash); err != nil {
+		r.log.Error("Ошибка при обновлении хеша пароля:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// DeleteTokenByID удаляет все сессии пользователя (выход на всех устройствах).
 func (r *AuthRepository) DeleteTokenByID(userID int) error {
 	query := `DELETE FROM tokens WHERE user_id = $1`
 
@@ -166,23 +295,149 @@ func (r *AuthRepository) DeleteTokenByID(userID int) error {
 	return nil
 }
 
-func (r *AuthRepository) GetTokenExpiryTime(userID int) (*entities.RefreshClaim, error) {
-	var claim entities.RefreshClaim
-	var expiresAt time.Time
+// DeleteSession удаляет одну сессию пользователя.
+func (r *AuthRepository) DeleteSession(userID int, sessionID string) error {
+	query := `DELETE FROM tokens WHERE user_id = $1 AND session_id = $2`
+
+	// Выполняем запрос в базу данных
+	res, err := r.db.Exec(query, userID, sessionID)
+	if err != nil {
+		r.log.Error("ошибка при удалени сессии из tokens таблицы:", err)
+		return errInternal
+	}
+
+	return r.checkAffected(res)
+}
+
+// DeleteOtherSessions удаляет все сессии пользователя, кроме указанной (текущей).
+func (r *AuthRepository) DeleteOtherSessions(userID int, keepSessionID string) error {
+	query := `DELETE FROM tokens WHERE user_id = $1 AND session_id <> $2`
+
+	// Выполняем запрос в базу данных
+	if _, err := r.db.Exec(query, userID, keepSessionID); err != nil {
+		r.log.Error("ошибка при удалени информации из tokens таблицы:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// GetSession возвращает сессию по её ID.
+func (r *AuthRepository) GetSession(sessionID string) (*entities.Session, error) {
+	return r.getSessionBy("session_id", sessionID)
+}
+
+// GetSessionByTokenHash возвращает сессию, текущий refresh токен которой имеет указанный хеш.
+func (r *AuthRepository) GetSessionByTokenHash(tokenHash string) (*entities.Session, error) {
+	return r.getSessionBy("token_hash", tokenHash)
+}
+
+// getSessionBy ищет сессию по значению одного из уникальных столбцов (session_id или token_hash).
+func (r *AuthRepository) getSessionBy(column, value string) (*entities.Session, error) {
+	var session entities.Session
+
+	query := fmt.Sprintf(`
+		SELECT session_id, user_id, token_hash, user_agent, COALESCE(host(ip_address), ''), created_at, last_used_at, expires_at
+		FROM tokens WHERE %s = $1
+	`, column)
 
-	query := `SELECT expires_at FROM tokens WHERE user_id = $1`
+	// Выполняем запрос в базу данных
+	err := r.db.QueryRow(query, value).Scan(&session.ID, &session.UserID, &session.TokenHash, &session.UserAgent,
+		&session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, errInternal
+	}
+
+	return &session, nil
+}
+
+// GetSessionsByUserID возвращает все действующие сессии пользователя, начиная с последней использованной.
+func (r *AuthRepository) GetSessionsByUserID(userID int) ([]entities.Session, error) {
+	query := `
+		SELECT session_id, user_id, user_agent, COALESCE(host(ip_address), ''), created_at, last_used_at, expires_at
+		FROM tokens WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
 
-	// Выполняем запрос в базу данных и получаем время истечения токена
-	err := r.db.QueryRow(query, userID).Scan(&expiresAt)
+	rows, err := r.db.Query(query, userID)
 	if err != nil {
 		r.log.Error("Ошибка при получении данных из DB:", err)
-		return nil, errors.New("пользователь не найден")
+		return nil, errInternal
+	}
+	defer rows.Close()
+
+	sessions := []entities.Session{}
+	for rows.Next() {
+		var session entities.Session
+		err = rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+		if err != nil {
+			r.log.Error("Ошибка при чтении данных из DB:", err)
+			return nil, errInternal
+		}
+		sessions = append(sessions, session)
 	}
 
-	// Преобразуем время в jwt.NumericDate
-	claim.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	if err = rows.Err(); err != nil {
+		r.log.Error("Ошибка при чтении данных из DB:", err)
+		return nil, errInternal
+	}
+
+	return sessions, nil
+}
+
+// checkAffected возвращает ErrSessionNotFound, если запрос не затронул ни одной строки.
+func (r *AuthRepository) checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		r.log.Error("Ошибка при получении количества изменённых строк:", err)
+		return errInternal
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// UpdateEmail меняет email пользователя. Если адрес уже занят (ограничение UNIQUE на users.email),
+// возвращается errEmail.
+func (r *AuthRepository) UpdateEmail(userID int, email string) error {
+	query := `UPDATE users SET email = $2 WHERE id = $1`
+
+	_, err := r.db.Exec(query, userID, email)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return errEmail
+		}
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// VerifyPhone отмечает номер телефона пользователя подтверждённым. Если этот номер уже подтвердил
+// другой пользователь (уникальный индекс по подтверждённым номерам), возвращается errPhone.
+func (r *AuthRepository) VerifyPhone(userID int) error {
+	query := `UPDATE users SET phone_verified = TRUE WHERE id = $1`
+
+	_, err := r.db.Exec(query, userID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return errPhone
+		}
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
 
-	return &claim, nil
+	return nil
 }
 
 // UpdateRecord обновляет поля в указанной таблице на основе данных, переданных в мапе.
@@ -212,11 +467,17 @@ func (r *AuthRepository) UpdateRecord(table string, fields map[string]interface{
 	return nil
 }
 
-func (r *AuthRepository) SaveLoginHistory(userID int, ipAddress string) error {
-	query := `INSERT INTO login_history (user_id, ip_address) VALUES ($1, $2)` // время Now() авт.
+// SaveLoginHistory сохраняет попытку входа (успешную или неудачную) в таблицу login_history.
+func (r *AuthRepository) SaveLoginHistory(entry *entities.LoginHistory) error {
+	query := `
+		INSERT INTO login_history (user_id, email, phone, ip_address, success, failure_reason, user_agent, device, os, session_id)
+		VALUES (NULLIF($1, 0), NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, '')::inet, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''))
+	` // время Now() авт.
 
 	// Выполняем запрос
-	if _, err := r.db.Exec(query, userID, ipAddress); err != nil {
+	_, err := r.db.Exec(query, entry.UserID, entry.Email, entry.Phone, entry.IPAddress, entry.Success, entry.Reason,
+		entry.UserAgent, entry.Device, entry.OS, entry.SessionID)
+	if err != nil {
 		r.log.Error("Ошибка при записи данных в таблицу login_history:", err)
 		return errInternal
 	}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"food-delivery/internal/auth/entities"
+)
+
+// GetUserIDByIdentity возвращает ID пользователя, привязанного к аккаунту внешнего провайдера.
+// Если привязки нет, возвращается sql.ErrNoRows.
+func (r *AuthRepository) GetUserIDByIdentity(provider, subject string) (int, error) {
+	var userID int
+
+	query := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	if err := r.db.QueryRow(query, provider, subject).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, err
+		}
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return 0, errInternal
+	}
+
+	return userID, nil
+}
+
+// CreateIdentity привязывает аккаунт внешнего провайдера к существующему пользователю.
+func (r *AuthRepository) CreateIdentity(userID int, provider, subject, email string) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, NULLIF($4, ''))`
+
+	if _, err := r.db.Exec(query, userID, provider, subject, email); err != nil {
+		r.log.Error("Ошибка при сохранении привязки к провайдеру:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity создаёт пользователя, вошедшего через внешнего провайдера, и привязку
+// к аккаунту провайдера одной транзакцией. Возвращает ID созданного пользователя.
+func (r *AuthRepository) CreateUserWithIdentity(user *entities.User, provider, subject string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Ошибка при открытии транзакции:", err)
+		return 0, errInternal
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(`INSERT INTO users (firstname, email, password_hash, locale) VALUES ($1, $2, $3, $4) RETURNING id`,
+		user.Firstname, user.Email, user.Password, user.Locale).Scan(&userID)
+	if err != nil {
+		r.log.Error("Ошибка при сохранении пользователя: ", err)
+		return 0, errInternal
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)`,
+		userID, provider, subject, user.Email)
+	if err != nil {
+		r.log.Error("Ошибка при сохранении привязки к провайдеру:", err)
+		return 0, errInternal
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Ошибка при подтверждении транзакции:", err)
+		return 0, errInternal
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"strings"
+)
+
+// GetLoginHistory возвращает страницу истории входов, отсортированную от новых записей к старым,
+// и общее количество записей, подходящих под фильтр.
+func (r *AuthRepository) GetLoginHistory(filter *entities.LoginHistoryFilter) ([]entities.LoginHistory, int, error) {
+	// Строим условия WHERE из заполненных полей фильтра
+	conditions := []string{}
+	values := []interface{}{}
+
+	if filter.UserID != 0 {
+		values = append(values, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(values)))
+	}
+	if filter.IP != "" {
+		// <<= находит как сам адрес, так и все адреса подсети
+		values = append(values, filter.IP)
+		conditions = append(conditions, fmt.Sprintf("ip_address <<= $%d::inet", len(values)))
+	}
+	if !filter.From.IsZero() {
+		values = append(values, filter.From)
+		conditions = append(conditions, fmt.Sprintf("login_time >= $%d", len(values)))
+	}
+	if !filter.To.IsZero() {
+		values = append(values, filter.To)
+		conditions = append(conditions, fmt.Sprintf("login_time < $%d", len(values)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM login_history "+where, values...).Scan(&total); err != nil {
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, 0, errInternal
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, COALESCE(user_id, 0), COALESCE(email, ''), COALESCE(phone, ''), COALESCE(host(ip_address), ''), COALESCE(user_agent, ''),
+		       COALESCE(device, ''), COALESCE(os, ''), COALESCE(session_id, ''), success, COALESCE(failure_reason, ''), login_time
+		FROM login_history
+		%s
+		ORDER BY login_time DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(values)+1, len(values)+2)
+
+	rows, err := r.db.Query(query, append(values, filter.Limit, filter.Offset)...)
+	if err != nil {
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, 0, errInternal
+	}
+	defer rows.Close()
+
+	history := []entities.LoginHistory{}
+	for rows.Next() {
+		var entry entities.LoginHistory
+		var loginTime sql.NullTime
+		err = rows.Scan(&entry.ID, &entry.UserID, &entry.Email, &entry.Phone, &entry.IPAddress, &entry.UserAgent,
+			&entry.Device, &entry.OS, &entry.SessionID, &entry.Success, &entry.Reason, &loginTime)
+		if err != nil {
+			r.log.Error("Ошибка при чтении данных из DB:", err)
+			return nil, 0, errInternal
+		}
+		entry.LoginTime = loginTime.Time
+		history = append(history, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log.Error("Ошибка при чтении данных из DB:", err)
+		return nil, 0, errInternal
+	}
+
+	return history, total, nil
+}
+
+// GetKnownLoginContext проверяет, входил ли пользователь ранее успешно с этого IP-адреса и с этого устройства.
+func (r *AuthRepository) GetKnownLoginContext(userID int, ipAddress, device, os string) (*entities.KnownLoginContext, error) {
+	var known entities.KnownLoginContext
+
+	query := `
+		SELECT COUNT(*) > 0,
+		       COALESCE(BOOL_OR(ip_address = NULLIF($2, '')::inet), FALSE),
+		       COALESCE(BOOL_OR(COALESCE(device, '') = $3 AND COALESCE(os, '') = $4), FALSE)
+		FROM login_history
+		WHERE user_id = $1 AND success
+	`
+
+	err := r.db.QueryRow(query, userID, ipAddress, device, os).Scan(&known.HasHistory, &known.KnownIP, &known.KnownDevice)
+	if err != nil {
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return nil, errInternal
+	}
+
+	return &known, nil
+}
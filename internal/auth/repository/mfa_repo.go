@@ -0,0 +1,85 @@
+package repository
+
+import "database/sql"
+
+// GetTOTPSecret возвращает секрет TOTP пользователя (пустую строку, если 2FA не подключалась).
+func (r *AuthRepository) GetTOTPSecret(userID int) (string, error) {
+	var secret sql.NullString
+
+	query := `SELECT totp_secret FROM users WHERE id = $1`
+
+	if err := r.db.QueryRow(query, userID).Scan(&secret); err != nil {
+		r.log.Error("Ошибка при получении данных из DB:", err)
+		return "", errInternal
+	}
+
+	return secret.String, nil
+}
+
+// SetTOTPSecret сохраняет новый (ещё не подтверждённый) секрет TOTP. 2FA остаётся выключенной до подтверждения.
+func (r *AuthRepository) SetTOTPSecret(userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = $2, totp_enabled = FALSE WHERE id = $1`
+
+	if _, err := r.db.Exec(query, userID, secret); err != nil {
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// EnableTOTP включает 2FA и заменяет коды восстановления пользователя одной транзакцией.
+func (r *AuthRepository) EnableTOTP(userID int, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		r.log.Error("Ошибка при открытии транзакции:", err)
+		return errInternal
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`UPDATE users SET totp_enabled = TRUE WHERE id = $1`, userID); err != nil {
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return errInternal
+	}
+
+	// Старые коды восстановления перестают действовать
+	if _, err = tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		r.log.Error("Ошибка при удалении кодов восстановления:", err)
+		return errInternal
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, codeHash)
+		if err != nil {
+			r.log.Error("Ошибка при сохранении кода восстановления:", err)
+			return errInternal
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("Ошибка при подтверждении транзакции:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// UseRecoveryCode помечает неиспользованный код восстановления как использованный.
+// Возвращает false, если такого кода нет или он уже был использован.
+func (r *AuthRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := `UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	res, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		r.log.Error("Ошибка при обновлении записи в таблице:", err)
+		return false, errInternal
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		r.log.Error("Ошибка при получении количества изменённых строк:", err)
+		return false, errInternal
+	}
+
+	return affected > 0, nil
+}
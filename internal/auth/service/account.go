@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	defaultDeletionGrace = (time.Hour * 24) * 30 // Срок до обезличивания удалённого аккаунта по умолчанию (30 дней)
+	ttlDeletionCode      = time.Minute * 15      // Время жизни кода подтверждения удаления аккаунта
+	ttlDeletionThrottle  = time.Minute           // Минимальный интервал между запросами кода удаления
+	errDeletionThrottled = errors.New("код уже отправлен, повторите запрос через минуту")
+	errAccountRemoved    = errors.New("аккаунт уже удалён")
+)
+
+func accountDeletionKey(userID int) string {
+	return fmt.Sprintf("account_deletion:%d", userID)
+}
+
+func accountDeletionThrottleKey(userID int) string {
+	return fmt.Sprintf("account_deletion_throttle:%d", userID)
+}
+
+// SendDeletionCode отправляет на email пользователя код подтверждения удаления аккаунта.
+// Код нужен аккаунтам без известного пользователю пароля (созданным через внешнего провайдера),
+// но принимается для любого аккаунта.
+func (s *AuthService) SendDeletionCode(claim *entities.AccessClaim) error {
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := s.client.SetNX(ctx, accountDeletionThrottleKey(user.ID), 1, ttlDeletionThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !allowed {
+		return errDeletionThrottled
+	}
+
+	code := utils.GenRandCode()
+	if err = s.saveCode(accountDeletionKey(user.ID), &codeEntry{UserID: user.ID, Code: code}, ttlDeletionCode); err != nil {
+		return err
+	}
+
+	if err = s.sendAccountDeletionEmail(user.Email, user.Locale, code); err != nil {
+		s.log.Error("Ошибка отправки кода удаления аккаунта:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// DeleteAccount удаляет аккаунт по запросу самого пользователя после проверки пароля или кода из письма
+// (см. SendDeletionCode): все сессии завершаются, токены отзываются, пользователь переводится в статус removed.
+// Персональные данные обезличиваются позже, по истечении срока ожидания (см. AnonymizeRemovedUsers).
+func (s *AuthService) DeleteAccount(claim *entities.AccessClaim, request *entities.DeleteAccountRequest) error {
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return err
+	}
+
+	// Повторное удаление не должно сдвигать время удаления и срок обезличивания
+	if user.Status == entities.UserStatusRemoved {
+		return errAccountRemoved
+	}
+
+	// Удаление аккаунта необратимо, поэтому требуем подтверждение даже при действительном access токене
+	switch {
+	case request.Code != "":
+		key := accountDeletionKey(user.ID)
+		if _, err = s.checkCode(key, request.Code); err != nil {
+			return err
+		}
+		if err = s.consumeCode(key); err != nil {
+			return err
+		}
+	case request.Password != "":
+		// Попытки подтверждения паролем ограничены так же, как при смене пароля и email
+		if err = s.reauthenticate(user, request.Password, request.MFACode, request.RecoveryCode); err != nil {
+			return err
+		}
+	default:
+		return errInvalidData
+	}
+
+	if err = s.repo.MarkUserRemoved(user.ID); err != nil {
+		return err
+	}
+
+	return s.TerminateUserSessions(user.ID)
+}
+
+// AnonymizeRemovedUsers обезличивает аккаунты, удалённые раньше, чем срок ожидания назад.
+// Срок задаётся в днях переменной ACCOUNT_DELETION_GRACE_DAYS (по умолчанию 30).
+func (s *AuthService) AnonymizeRemovedUsers() (int, error) {
+	return s.repo.AnonymizeRemovedUsers(time.Now().Add(-deletionGrace()))
+}
+
+// StartAnonymizer запускает фоновое обезличивание удалённых аккаунтов с указанным интервалом.
+func (s *AuthService) StartAnonymizer(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			count, err := s.AnonymizeRemovedUsers()
+			if err != nil {
+				s.log.Error("Ошибка при обезличивании удалённых аккаунтов:", err)
+				continue
+			}
+			if count > 0 {
+				s.log.Info(fmt.Sprintf("обезличено удалённых аккаунтов: %d", count))
+			}
+		}
+	}()
+}
+
+func deletionGrace() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		return defaultDeletionGrace
+	}
+
+	return (time.Hour * 24) * time.Duration(days)
+}
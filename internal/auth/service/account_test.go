@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"testing"
+)
+
+func TestDeleteAccountAlreadyRemoved(t *testing.T) {
+	repo := newFakeAccountRepo()
+	s := newTestOIDCService(t, repo, nil, nil)
+	user := newTestUser(t, s, 5, "removed@example.com", "correct horse battery staple")
+	user.Status = entities.UserStatusRemoved
+	repo.users[user.ID] = user
+
+	err := s.DeleteAccount(&entities.AccessClaim{ID: user.ID}, &entities.DeleteAccountRequest{Password: "correct horse battery staple"})
+	if !errors.Is(err, errAccountRemoved) {
+		t.Errorf("ошибка %v, ожидалась errAccountRemoved", err)
+	}
+}
+
+func TestDeleteAccountAttemptLimit(t *testing.T) {
+	client := testRedis(t)
+
+	repo := newFakeAccountRepo()
+	s := newTestOIDCService(t, repo, client, nil)
+	user := newTestUser(t, s, 6, "delete-limit@example.com", "correct horse battery staple")
+	repo.users[user.ID] = user
+	t.Cleanup(func() { client.Del(ctx, reauthAttemptsKey(user.ID)) })
+
+	claim := &entities.AccessClaim{ID: user.ID}
+	for i := 0; i < maxReauthAttempts; i++ {
+		err := s.DeleteAccount(claim, &entities.DeleteAccountRequest{Password: "wrong"})
+		if !errors.Is(err, errWrongPassword) {
+			t.Fatalf("попытка %d: ошибка %v, ожидалась errWrongPassword", i+1, err)
+		}
+	}
+
+	// После исчерпания попыток не принимается даже верный пароль
+	err := s.DeleteAccount(claim, &entities.DeleteAccountRequest{Password: "correct horse battery staple"})
+	if !errors.Is(err, errReauthLocked) {
+		t.Errorf("ошибка %v, ожидалась errReauthLocked", err)
+	}
+	if user.Status != entities.UserStatusActive {
+		t.Errorf("статус %q после исчерпания попыток", user.Status)
+	}
+}
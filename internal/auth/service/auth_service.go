@@ -2,21 +2,25 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
 	"errors"
+	"fmt"
 	"food-delivery/internal/auth/entities"
 	"food-delivery/internal/auth/repository"
+	"food-delivery/pkg/hasher"
 	"food-delivery/pkg/logger"
+	"food-delivery/pkg/mailer"
 	"food-delivery/pkg/middlewares"
+	"food-delivery/pkg/oidc"
+	"food-delivery/pkg/passpolicy"
+	"food-delivery/pkg/sms"
 	"food-delivery/pkg/utils"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
-	"net"
 	"time"
 )
 
 var (
-	ttl                     = time.Minute * 2
+	ttl                     = time.Minute * 2 // Время жизни кода подтверждения email
 	ctx                     = context.Background()
 	errInternal             = errors.New("произошла внутренняя ошибка")
 	errInvalidData          = errors.New("невалидные данные")
@@ -26,166 +30,296 @@ var (
 )
 
 type AuthServiceInt interface {
-	Register(user *entities.User) error
-	ConfirmEmail(code string) error
-	SignIn(user *entities.User, userAddr string) (*entities.TokensResponse, error)
+	Register(user *entities.User) (string, error)
+	ConfirmEmail(email, registrationToken, code string) error
+	ResendCode(email, registrationToken string) error
+	ConfirmPhone(email, code string) error
+	ResendPhoneCode(email, registrationToken string) error
+	RequestPhoneSignInCode(phone string) error
+	SignInByPhone(request *entities.PhoneSignInRequest, ipAddress, userAgent string) (*entities.TokensResponse, error)
+	SignIn(user *entities.User, ipAddress, userAgent string) (*entities.TokensResponse, error)
+	StartOIDCSignIn(provider string) (string, string, error)
+	FinishOIDCSignIn(provider, code, state, ipAddress, userAgent string) (*entities.TokensResponse, error)
 	RefreshTokens(refreshToken string) (*entities.TokensResponse, error)
-	SignOut(refreshToken string) error
+	SignOut(refreshToken, accessToken string) error
+	GetSessions(claim *entities.AccessClaim) ([]entities.Session, error)
+	DeleteSession(claim *entities.AccessClaim, sessionID string) error
+	SignOutAll(claim *entities.AccessClaim) error
+	TerminateUserSessions(userID int) error
+	GetLoginHistory(claim *entities.AccessClaim, page, limit int) (*entities.LoginHistoryPage, error)
+	SearchLoginHistory(filter *entities.LoginHistoryFilter, page, limit int) (*entities.LoginHistoryPage, error)
+	RevokeUserAccess(userID int) error
+	NotMe(token string) error
+	ForgotPassword(email string) error
+	ResetPassword(request *entities.ResetPasswordRequest) error
+	ChangePassword(claim *entities.AccessClaim, request *entities.ChangePasswordRequest) error
+	ChangeEmail(claim *entities.AccessClaim, request *entities.ChangeEmailRequest) error
+	ConfirmEmailChange(claim *entities.AccessClaim, code string) error
+	SendDeletionCode(claim *entities.AccessClaim) error
+	DeleteAccount(claim *entities.AccessClaim, request *entities.DeleteAccountRequest) error
+	AnonymizeRemovedUsers() (int, error)
+	ChangeUserStatus(userID int, status string) error
+	GetPresence(userID int) (*entities.Presence, error)
+	EnrollTOTP(claim *entities.AccessClaim) (*entities.TOTPEnrollResponse, error)
+	VerifyTOTP(claim *entities.AccessClaim, code string) (*entities.RecoveryCodesResponse, error)
+	SignInMFA(request *entities.MFASignInRequest) (*entities.TokensResponse, error)
 }
 
 type AuthService struct {
 	repo   repository.AuthRepoInt
 	client *redis.Client
+	sms    sms.SMSSender
+	oidc   map[string]*oidc.Client
+	hasher hasher.Hasher
+	policy *passpolicy.Policy
+	mailer *mailer.Mailer
 	log    *logger.Logger
 }
 
-func NewAuthService(repo repository.AuthRepoInt, client *redis.Client, log *logger.Logger) *AuthService {
+func NewAuthService(repo repository.AuthRepoInt, client *redis.Client, smsSender sms.SMSSender,
+	oidcClients map[string]*oidc.Client, passwordHasher hasher.Hasher, passwordPolicy *passpolicy.Policy,
+	emailSender *mailer.Mailer, log *logger.Logger) *AuthService {
 	return &AuthService{
 		repo:   repo,
 		client: client,
+		sms:    smsSender,
+		oidc:   oidcClients,
+		hasher: passwordHasher,
+		policy: passwordPolicy,
+		mailer: emailSender,
 		log:    log,
 	}
 }
 
-func (s *AuthService) Register(user *entities.User) error {
+// Register начинает регистрацию: сохраняет её в Redis, отправляет код подтверждения на email и код по SMS.
+// Возвращает токен регистрации, который нужен для её подтверждения и повторной отправки кода.
+func (s *AuthService) Register(user *entities.User) (string, error) {
 	// Валидируем данные пользователя.
 	if err := utils.ValidateUserForRegister(user); err != nil {
 		s.log.Error("невалидные данные:", err)
-		return err
+		return "", err
+	}
+	if err := s.checkPasswordPolicy(user); err != nil {
+		return "", err
 	}
 
+	// Приводим номер телефона к формату E.164, чтобы один номер не регистрировался в разных записях.
+	phone, err := utils.NormalizePhone(user.Phone)
+	if err != nil {
+		return "", err
+	}
+	user.Phone = phone
+
+	// Письма отправляются на выбранном пользователем языке (или на языке по умолчанию)
+	user.Locale = s.mailer.Locale(user.Locale)
+
 	// Проверяем, существует ли пользователь с такими данными (email или телефон) в базе данных.
-	if err := s.repo.TestData(user); err != nil {
-		return err
+	if err = s.repo.TestData(user); err != nil {
+		return "", err
+	}
+
+	// Новая регистрация заменяет незавершённую регистрацию с этим email: завершить её может только тот,
+	// у кого есть токен регистрации, поэтому чужая регистрация не может занять email владельца.
+	// Письма с кодом на один email отправляются не чаще раза в минуту, в том числе при повторной регистрации.
+	if err = s.takeResendSlot(user.Email); err != nil {
+		return "", err
 	}
 
 	// Хешируем пароль пользователя для безопасности.
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	passwordHash, err := s.hasher.Hash(user.Password)
 	if err != nil {
 		s.log.Error("ошибка хеширования пароля:", err)
-		return errInternal
+		return "", errInternal
 	}
-	user.Password = string(passwordHash)
+	user.Password = passwordHash
 
-	// Генерируем случайный код подтверждения, который будет отправлен на email.
-	code := utils.GenRandCode()
-
-	// Сериализуем данные пользователя в JSON, чтобы сохранить их в Redis.
-	userJSON, err := json.Marshal(user)
+	registrationToken, err := utils.GenRandToken(32)
 	if err != nil {
-		s.log.Error("не удалось сериализовать данные пользователя:", err)
-		return errInternal
+		s.log.Error("ошибка генерации токена регистрации:", err)
+		return "", errInternal
 	}
 
-	// Сохраняем данные пользователя в Redis с установленным временем жизни (TTL).
-	if err = s.client.Set(ctx, code, userJSON, ttl).Err(); err != nil {
-		s.log.Error("Ошибка при записи данных в Redis:", err)
-		return errInternal
+	// Сохраняем незавершённую регистрацию в Redis и отправляем код подтверждения на email.
+	reg := &pendingRegistration{User: *user, TokenHash: utils.HashToken(registrationToken)}
+	if err = s.createRegistration(reg); err != nil {
+		return "", err
 	}
 
-	// Отправляем (ассинхронно) код подтверждения на email пользователя.
-	if err = SendConfirmationEmail(user.Email, code); err != nil {
-		s.log.Error("Ошибка отправки кода подтверждения на email:", err)
+	// Отправляем код подтверждения номера телефона по SMS.
+	if err = s.sendPhoneVerificationCode(user.Email, user.Phone); err != nil {
+		return "", err
 	}
 
-	// Если регистрация прошла успешно, возвращаем nil.
-	return nil
+	return registrationToken, nil
 }
 
-func (s *AuthService) ConfirmEmail(code string) error {
-	// Проверяем, что код подтверждения не пустой.
-	if code == "" {
+func (s *AuthService) ConfirmEmail(email, registrationToken, code string) error {
+	// Проверяем, что email и код подтверждения не пустые.
+	if email == "" || code == "" {
 		return errInvalidData
 	}
 
-	// Получаем данные из Redis по коду подтверждения.
-	result, err := s.client.Get(ctx, code).Result()
-	if err == redis.Nil {
-		// Если данных нет или срок действия истёк, возвращаем ошибку.
-		return errors.New("код подтверждения не найден или срок действия истек")
-	}
+	// Получаем незавершённую регистрацию из Redis: только ту, что начата с этим токеном.
+	reg, err := s.getOwnRegistration(email, registrationToken)
 	if err != nil {
-		s.log.Error("Ошибка при получении данных из Redis:", err)
-		return errors.New("ошибка проверки кода подтверждения")
+		return err
 	}
 
-	// Декодируем данные пользователя из строки JSON.
-	var user entities.User
-	if err = json.Unmarshal([]byte(result), &user); err != nil {
-		s.log.Error("Ошибка при декодировании данных из Redis:", err)
-		return errors.New("не удалось обработать данные пользователя")
+	// Проверяем код подтверждения (неверные попытки учитываются).
+	if err = s.checkRegistrationCode(reg, code); err != nil {
+		return err
 	}
 
 	// Сохраняем пользователя в базе данных.
-	if err = s.repo.SaveUser(&user); err != nil {
+	reg.User.PhoneVerified = reg.PhoneVerified
+	if err = s.repo.SaveUser(&reg.User); err != nil {
 		return err
 	}
 
+	// Регистрация завершена, удаляем её из Redis.
+	s.deleteRegistration(reg)
+
 	// Если всё прошло успешно, возвращаем nil.
 	return nil
 }
 
-func (s *AuthService) SignIn(user *entities.User, userAddr string) (*entities.TokensResponse, error) {
+// SignIn выполняет вход по email и паролю. ipAddress — реальный IP-адрес клиента
+// (определяется middlewares.ClientIPMiddleware с учётом доверенных прокси), может быть пустым.
+func (s *AuthService) SignIn(user *entities.User, ipAddress, userAgent string) (*entities.TokensResponse, error) {
+	guardEmail := normalizeEmail(user.Email)
+
+	// Защита от подбора пароля: проверяем блокировки и задержки для email и IP
+	if err := s.checkSignInAllowed(guardEmail, ipAddress); err != nil {
+		s.saveLoginAttempt(&entities.LoginHistory{
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailLocked,
+		})
+		return nil, err
+	}
+
 	// Вытаскиваем данные пользователя по email из репозитория
 	resUser, err := s.repo.DBVerifyUser(user)
+	if err == sql.ErrNoRows {
+		s.registerFailedSignIn(guardEmail, &entities.LoginHistory{
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailUnknownEmail,
+		})
+		return nil, errIncorrectPasAndEmail
+	}
 	if err != nil {
 		return nil, errIncorrectPasAndEmail
 	}
 	resUser.Email = user.Email
 
-	if resUser.Status == "blocked" || resUser.Status == "removed" {
-		s.log.Error("Попытка входа заблокированного или удалённого пользователя", nil)
-		return nil, errors.New("пользователь удалён или заблокирован")
+	if !entities.CanSignIn(resUser.Status) {
+		s.log.Info(fmt.Sprintf("попытка входа в заблокированный, приостановленный или удалённый аккаунт (user_id=%d)", resUser.ID))
+		s.saveLoginAttempt(&entities.LoginHistory{
+			UserID:    resUser.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailBlocked,
+		})
+		return nil, errAccountUnavailable
 	}
 
 	// Проверяем соответствие пароля с хешированным паролем в базе данных
-	err = bcrypt.CompareHashAndPassword([]byte(resUser.Password), []byte(user.Password))
+	err = s.hasher.Compare(resUser.Password, user.Password)
 	if err != nil {
 		s.log.Error("Ошибка проверки подленности пароля", err)
+		s.registerFailedSignIn(guardEmail, &entities.LoginHistory{
+			UserID:    resUser.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailWrongPassword,
+		})
 		return nil, errIncorrectPasAndEmail
 	}
 
-	// Обновляем статус пользователя на 'active', только если пароль верен
-	err = s.repo.UpdateRecord("users", map[string]interface{}{
-		"status": "active",
-	}, resUser.ID)
+	// Хеш, посчитанный устаревшим алгоритмом или с устаревшими параметрами, незаметно пересчитываем
+	s.upgradePasswordHash(resUser.ID, user.Password, resUser.Password)
+
+	// Пользователь сообщил, что вход был выполнен не им: пароль мог быть скомпрометирован
+	if resUser.PasswordResetRequired {
+		return nil, errPasswordResetRequired
+	}
+
+	// Если у пользователя включена 2FA, вместо токенов выдаём MFA-челлендж.
+	// Счётчик неудачных попыток сбрасывается только после проверки второго фактора.
+	if resUser.TOTPEnabled {
+		return s.createMFAChallenge(resUser, guardEmail, "", ipAddress, userAgent)
+	}
+
+	// Пароль верен: сбрасываем счётчик неудачных попыток
+	s.resetSignInFailures(guardEmail)
+
+	return s.startSession(resUser, "", ipAddress, userAgent)
+}
+
+// startSession завершает вход пользователя: создаёт сессию устройства, выдаёт токены
+// и записывает вход в login_history. phone — телефон, если вход выполнен по SMS.
+func (s *AuthService) startSession(resUser *entities.User, phone, ipAddress, userAgent string) (*entities.TokensResponse, error) {
+	// Каждый вход создаёт отдельную сессию, чтобы не завершать сессии на других устройствах
+	sessionID, err := utils.GenRandToken(16)
 	if err != nil {
-		return nil, err
+		s.log.Error("ошибка при генерации ID сессии:", err)
+		return nil, errInternal
 	}
 
 	// Генерация access токена
-	accessToken, err := utils.GenerateAccessToken(resUser, ttlAccess)
+	accessToken, err := utils.GenerateSessionAccessToken(resUser, sessionID, ttlAccess)
 	if err != nil {
 		s.log.Error("ошибка при генерации access токена:", err)
 		return nil, err
 	}
 
-	// Генерация refresh токена
-	refreshToken, expiresAt, err := utils.GenerateRefreshToken(resUser, ttlRefresh)
+	// Генерация refresh токена, привязанного к сессии
+	refreshToken, expiresAt, err := utils.GenerateSessionRefreshToken(resUser, sessionID, ttlRefresh)
 	if err != nil {
 		s.log.Error("ошибка при генерации refresh токена:", err)
 		return nil, err
 	}
 
-	// Сохранение данных о токенах в таблицу tokens
-	err = s.repo.PersistToken(resUser.ID, refreshToken, expiresAt)
+	// Сохранение сессии в таблицу tokens
+	err = s.repo.CreateSession(&entities.Session{
+		ID:        sessionID,
+		UserID:    resUser.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: expiresAt,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	addr, err := net.ResolveTCPAddr("tcp", userAddr)
-	if err != nil {
-		s.log.Error("Ошибка при разборе адреса:", err)
-		return nil, errInternal
+	// Сохранение данных о входе в таблицу login_history
+	entry := &entities.LoginHistory{
+		UserID:    resUser.ID,
+		Email:     resUser.Email,
+		Phone:     phone,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		SessionID: sessionID,
+		Success:   true,
 	}
-	ipAddress := addr.IP.String()
+	entry.Device, entry.OS = utils.ParseUserAgent(userAgent)
 
-	// Сохранение данных о входе в таблицу login_history
-	err = s.repo.SaveLoginHistory(resUser.ID, ipAddress)
-	if err != nil {
+	// Предупреждаем пользователя о входе с нового IP-адреса или устройства (до записи текущего входа)
+	s.notifyIfNewSignIn(resUser, entry)
+
+	if err = s.repo.SaveLoginHistory(entry); err != nil {
 		return nil, err
 	}
 
+	// Вход отмечает пользователя в сети, статус аккаунта при этом не меняется
+	s.touchPresence(resUser.ID)
+
 	// Возвращаем успешный ответ с токенами
 	response := &entities.TokensResponse{
 		AccessToken:  accessToken,
@@ -195,85 +329,84 @@ func (s *AuthService) SignIn(user *entities.User, userAddr string) (*entities.To
 	return response, nil
 }
 
-func (s *AuthService) SignOut(refreshToken string) error {
-	// Разбор refresh токена
-	tokenClaim, err := middlewares.ParseRefreshToken(refreshToken)
+func (s *AuthService) SignOut(refreshToken, accessToken string) error {
+	// Проверяем refresh токен и находим его сессию
+	tokenClaim, err := s.verifySessionToken(refreshToken)
 	if err != nil {
-		s.log.Error("ошибка при парсинге refresh токена:", err)
 		return err
 	}
 
-	// Обновляем статус пользователя на 'active', только если пароль верен
-	err = s.repo.UpdateRecord("users", map[string]interface{}{
-		"status": "suspended",
-	}, tokenClaim.ID)
-	if err != nil {
+	// Удаление сессии текущего устройства из базы данных
+	if err = s.repo.DeleteSession(tokenClaim.ID, tokenClaim.SessionID); err != nil {
 		return err
 	}
 
-	// Удаление токена из базы данных
-	if err := s.repo.DeleteTokenByID(tokenClaim.ID); err != nil {
-		return err
+	// Отзываем access токен, с которым пришёл запрос, и все остальные access токены этой сессии
+	if accessToken != "" {
+		if accessClaim, err := middlewares.ParseAccessToken(accessToken); err == nil && accessClaim.ID == tokenClaim.ID {
+			if err = middlewares.RevokeAccessToken(ctx, s.client, accessClaim); err != nil {
+				s.log.Error("Ошибка при записи данных в Redis:", err)
+				return errInternal
+			}
+		}
 	}
 
-	return nil
+	// Если это была последняя сессия, пользователь больше не в сети
+	s.setOfflineIfSignedOut(tokenClaim.ID)
+
+	return s.revokeSessionAccess(tokenClaim.SessionID)
 }
 
 func (s *AuthService) RefreshTokens(refreshToken string) (*entities.TokensResponse, error) {
-	// Валидация refresh токена
-	if err := middlewares.ValidateRefreshToken(refreshToken); err != nil {
-		s.log.Error("ошибка при валидации refresh токена:", err)
-		return nil, err
-	}
-
-	// Разбор refresh токена
-	tokenClaim, err := middlewares.ParseRefreshToken(refreshToken)
+	// Проверяем refresh токен и находим его сессию
+	tokenClaim, err := s.verifySessionToken(refreshToken)
 	if err != nil {
-		s.log.Error("ошибка при парсинге refresh токена:", err)
 		return nil, err
 	}
 
-	// Запрос в базу данных для получения времени протухания токена по ID
-	tokenInDB, err := s.repo.GetTokenExpiryTime(tokenClaim.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Проверка на то, что токен ещё не протух
-	if tokenClaim.ExpiresAt == tokenInDB.ExpiresAt {
-		return nil, errors.New("время действия токена просрочено")
-	}
-
 	// Запрос в базу данных для получения данных пользователя
 	user, err := s.repo.GetUserByID(tokenClaim.ID)
 	if err != nil {
 		return nil, errInternal
 	}
 
+	// Сессии приостановленных и заблокированных пользователей завершаются при смене статуса,
+	// но на случай гонки не продлеваем их и здесь
+	if !entities.CanSignIn(user.Status) {
+		return nil, errAccountUnavailable
+	}
+
 	// Генерация access токена
-	accessToken, err := utils.GenerateAccessToken(user, ttlAccess)
+	accessToken, err := utils.GenerateSessionAccessToken(user, tokenClaim.SessionID, ttlAccess)
 	if err != nil {
 		s.log.Error("ошибка при генерации access токена:", err)
 		return nil, err
 	}
 
-	// Генерация refresh токена
-	refreshToken, expiresAt, err := utils.GenerateRefreshToken(user, ttlRefresh)
+	// Генерация нового refresh токена для той же сессии (того же семейства токенов)
+	newRefreshToken, expiresAt, err := utils.GenerateSessionRefreshToken(user, tokenClaim.SessionID, ttlRefresh)
 	if err != nil {
 		s.log.Error("ошибка при генерации refresh токена:", err)
 		return nil, err
 	}
 
-	// Сохранение данных о токенах в таблицу tokens
-	err = s.repo.PersistToken(user.ID, refreshToken, expiresAt)
+	// Ротация: предъявленный токен потребляется и заменяется новым
+	err = s.repo.RotateToken(tokenClaim.SessionID, utils.HashToken(refreshToken), utils.HashToken(newRefreshToken), expiresAt)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		// Токен уже был потреблён параллельным запросом — это повторное использование
+		s.revokeTokenFamily(tokenClaim)
+		return nil, errTokenReused
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	s.touchPresence(user.ID)
+
 	// Возвращаем успешный ответ с токенами
 	response := &entities.TokensResponse{
 		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		RefreshToken: newRefreshToken,
 	}
 
 	return response, nil
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+const maxCodeAttempts = 5 // Количество неверных попыток ввода кода, после которого код аннулируется
+
+var (
+	errCodeNotFound    = errors.New("код не найден или срок его действия истек")
+	errWrongCode       = errors.New("неверный код")
+	errTooManyAttempts = errors.New("превышено количество попыток ввода кода, запросите новый код")
+)
+
+// codeEntry описывает одноразовый код, хранящийся в Redis.
+type codeEntry struct {
+	UserID int    `json:"userId,omitempty"` // ID пользователя, для которого выпущен код
+	Email  string `json:"email,omitempty"`  // Новый email (для кода смены email)
+	Code   string `json:"code"`             // Сам код
+}
+
+// codeAttemptScript атомарно увеличивает счётчик попыток ввода кода (KEYS[2]) и выставляет ему
+// то же время жизни, что осталось у самого кода (KEYS[1]). Если кода нет, возвращает -1,
+// а счётчик не создаётся.
+var codeAttemptScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -2 then
+	return -1
+end
+local attempt = redis.call('INCR', KEYS[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return attempt
+`)
+
+// codeAttemptsKey — ключ счётчика попыток ввода для кода, хранящегося под ключом key.
+// Счётчик хранится отдельно от кода, поэтому сам код никогда не перезаписывается при проверке.
+func codeAttemptsKey(key string) string {
+	return key + ":attempts"
+}
+
+// saveCode сохраняет код в Redis с указанным временем жизни, заменяя предыдущий код
+// и сбрасывая счётчик попыток.
+func (s *AuthService) saveCode(key string, entry *codeEntry, ttl time.Duration) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		s.log.Error("не удалось сериализовать код:", err)
+		return errInternal
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, entryJSON, ttl)
+		pipe.Del(ctx, codeAttemptsKey(key))
+		return nil
+	})
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// takeCodeAttempt учитывает попытку ввода кода, хранящегося под ключом key, ещё до сравнения кода,
+// поэтому параллельные запросы не могут проверить больше maxCodeAttempts вариантов.
+// Возвращает true, если это последняя разрешённая попытка. Если кода нет, возвращается errCodeNotFound,
+// если попытки исчерпаны — код удаляется и возвращается errTooManyAttempts.
+func (s *AuthService) takeCodeAttempt(key string) (bool, error) {
+	attempt, err := codeAttemptScript.Run(ctx, s.client, []string{key, codeAttemptsKey(key)}).Int()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return false, errInternal
+	}
+	if attempt < 0 {
+		return false, errCodeNotFound
+	}
+	if attempt > maxCodeAttempts {
+		s.dropCode(key)
+		return false, errTooManyAttempts
+	}
+
+	return attempt == maxCodeAttempts, nil
+}
+
+// checkCode сверяет переданный код с сохранённым под ключом key.
+// Каждая попытка учитывается, после maxCodeAttempts неверных попыток код удаляется.
+// Сам код при успешной проверке не удаляется — для этого используется consumeCode.
+func (s *AuthService) checkCode(key, code string) (*codeEntry, error) {
+	last, err := s.takeCodeAttempt(key)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, errCodeNotFound
+	}
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	var entry codeEntry
+	if err = json.Unmarshal([]byte(result), &entry); err != nil {
+		s.log.Error("Ошибка при декодировании данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	// Сравнение за постоянное время, чтобы код нельзя было подобрать по времени ответа
+	if code == "" || subtle.ConstantTimeCompare([]byte(entry.Code), []byte(code)) != 1 {
+		if last {
+			s.dropCode(key)
+			return nil, errTooManyAttempts
+		}
+		return nil, errWrongCode
+	}
+
+	return &entry, nil
+}
+
+// consumeCode удаляет код, делая его одноразовым. Если код уже был использован
+// параллельным запросом, возвращается errCodeNotFound.
+func (s *AuthService) consumeCode(key string) error {
+	deleted, err := s.client.Del(ctx, key).Result()
+	if err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+		return errInternal
+	}
+	if deleted == 0 {
+		return errCodeNotFound
+	}
+
+	if err = s.client.Del(ctx, codeAttemptsKey(key)).Err(); err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+	}
+
+	return nil
+}
+
+// dropCode аннулирует код вместе со счётчиком попыток.
+func (s *AuthService) dropCode(key string) {
+	if err := s.client.Del(ctx, key, codeAttemptsKey(key)).Err(); err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+	}
+}
@@ -1,38 +1,57 @@
 package service
 
 import (
-	"fmt"
-	"net/smtp"
-	"os"
-	"strings"
+	"food-delivery/pkg/mailer"
+	"time"
 )
 
-// SendConfirmationEmail отправляет электронное письмо с кодом подтверждения на указанный адрес.
-// to - адрес электронной почты получателя, code - код подтверждения, который будет отправлен в письме.
-func SendConfirmationEmail(to, code string) error {
+// sendConfirmationEmail отправляет письмо с кодом подтверждения email.
+// ttlCode — время жизни кода, указывается в письме.
+func (s *AuthService) sendConfirmationEmail(to, locale, code string, ttlCode time.Duration) error {
+	return s.mailer.Send(to, locale, mailer.TemplateConfirmation, mailer.CodeData{
+		Code:       code,
+		TTLMinutes: int(ttlCode / time.Minute),
+	})
+}
 
-	from := os.Getenv("MAIL_FROM")         // Адрес электронной почты отправителя
-	password := os.Getenv("MAIL_PASSWORD") // Пароль для SMTP-сервера
-	smtpHost := os.Getenv("MAIL_HOST")     // Хост SMTP-сервера
-	smtpPort := os.Getenv("MAIL_PORT")     // Порт SMTP-сервера
+// sendPasswordResetEmail отправляет письмо с одноразовым кодом для сброса пароля.
+func (s *AuthService) sendPasswordResetEmail(to, locale, code string) error {
+	return s.mailer.Send(to, locale, mailer.TemplatePasswordReset, mailer.CodeData{
+		Code:       code,
+		TTLMinutes: int(ttlResetCode / time.Minute),
+	})
+}
 
-	// Формируем сообщение, включая заголовок и тело письма
-	var builder strings.Builder
-	builder.WriteString("Subject: Подтверждение регистрации\n\n")
-	builder.WriteString(fmt.Sprintf("Ваш код подтверждения: %s", code))
+// sendAccountDeletionEmail отправляет письмо с кодом подтверждения удаления аккаунта.
+func (s *AuthService) sendAccountDeletionEmail(to, locale, code string) error {
+	return s.mailer.Send(to, locale, mailer.TemplateAccountDeletion, mailer.CodeData{
+		Code:       code,
+		TTLMinutes: int(ttlDeletionCode / time.Minute),
+	})
+}
 
-	msg := []byte(builder.String())
+// sendPasswordChangedEmail уведомляет владельца аккаунта о том, что его пароль был изменён.
+func (s *AuthService) sendPasswordChangedEmail(to, locale string) error {
+	return s.mailer.Send(to, locale, mailer.TemplatePasswordChanged, nil)
+}
 
-	// Настраиваем аутентификацию для отправки почты
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+// sendEmailChangedEmail уведомляет прежний адрес о том, что email аккаунта был изменён.
+func (s *AuthService) sendEmailChangedEmail(to, locale, newEmail string) error {
+	return s.mailer.Send(to, locale, mailer.TemplateEmailChanged, mailer.EmailChangedData{NewEmail: newEmail})
+}
 
-	go func() {
-		// Отправляем почту с использованием smtp.SendMail
-		err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, msg)
-		if err != nil { //TODO: надо доработать логику (пока так)
-			err = smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, msg)
-		}
-	}()
+// sendEmailChangeRequestedEmail предупреждает прежний адрес о том, что начата смена email аккаунта.
+func (s *AuthService) sendEmailChangeRequestedEmail(to, locale, newEmail string) error {
+	return s.mailer.Send(to, locale, mailer.TemplateEmailChangeRequested, mailer.EmailChangedData{NewEmail: newEmail})
+}
 
-	return nil // Возвращаем nil, если отправка прошла успешно
+// sendNewSignInEmail предупреждает о входе в аккаунт с нового IP-адреса или устройства.
+// link — ссылка «это был не я», завершающая все сессии и требующая сброса пароля.
+func (s *AuthService) sendNewSignInEmail(to, locale, ipAddress, device, link string) error {
+	return s.mailer.Send(to, locale, mailer.TemplateNewSignIn, mailer.NewSignInData{
+		IPAddress: ipAddress,
+		Device:    device,
+		Time:      time.Now(),
+		Link:      link,
+	})
 }
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var (
+	ttlEmailChange          = time.Minute * 15 // Время жизни кода подтверждения нового email
+	ttlEmailChangeThrottle  = time.Minute      // Минимальный интервал между запросами смены email
+	errEmailChangeThrottled = errors.New("код уже отправлен, повторите запрос через минуту")
+	errEmailInvalid         = errors.New("некорректный email")
+	errEmailSame            = errors.New("новый email совпадает с текущим")
+	errEmailTaken           = errors.New("пользователь с таким email уже существует")
+)
+
+func emailChangeKey(userID int) string {
+	return fmt.Sprintf("email_change:%d", userID)
+}
+
+func emailChangeThrottleKey(userID int) string {
+	return fmt.Sprintf("email_change_throttle:%d", userID)
+}
+
+// ChangeEmail начинает смену email: после проверки текущего пароля (и второго фактора, если включена 2FA)
+// отправляет код подтверждения на новый адрес и предупреждает прежний адрес о начатой смене.
+// До подтверждения кода пользователь продолжает пользоваться прежним email.
+func (s *AuthService) ChangeEmail(claim *entities.AccessClaim, request *entities.ChangeEmailRequest) error {
+	newEmail := strings.TrimSpace(request.NewEmail)
+	if addr, err := mail.ParseAddress(newEmail); err != nil || addr.Address != newEmail {
+		return errEmailInvalid
+	}
+
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return errEmailSame
+	}
+
+	// Одного access токена недостаточно: email — это доступ к восстановлению пароля
+	if err = s.reauthenticate(user, request.CurrentPassword, request.Code, request.RecoveryCode); err != nil {
+		return err
+	}
+
+	// Адрес не должен быть занят другим пользователем (окончательно это проверит ограничение UNIQUE)
+	if _, err = s.repo.GetUserByEmail(newEmail); err == nil {
+		return errEmailTaken
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	// Не даём засыпать чужой адрес письмами: не чаще одного кода в минуту
+	allowed, err := s.client.SetNX(ctx, emailChangeThrottleKey(user.ID), 1, ttlEmailChangeThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !allowed {
+		return errEmailChangeThrottled
+	}
+
+	// Новый запрос заменяет предыдущий вместе с его кодом
+	code := utils.GenRandCode()
+	if err = s.saveCode(emailChangeKey(user.ID), &codeEntry{UserID: user.ID, Email: newEmail, Code: code}, ttlEmailChange); err != nil {
+		return err
+	}
+
+	if err = s.sendConfirmationEmail(newEmail, user.Locale, code, ttlEmailChange); err != nil {
+		s.log.Error("Ошибка отправки кода подтверждения нового email:", err)
+		return errInternal
+	}
+
+	// Владелец узнаёт о смене до её подтверждения и успевает сменить пароль, если это не он
+	if err = s.sendEmailChangeRequestedEmail(user.Email, user.Locale, newEmail); err != nil {
+		s.log.Error("Ошибка отправки уведомления о начатой смене email:", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange подтверждает новый email кодом из письма, меняет email пользователя
+// и уведомляет об этом прежний адрес.
+func (s *AuthService) ConfirmEmailChange(claim *entities.AccessClaim, code string) error {
+	if code == "" {
+		return errInvalidData
+	}
+
+	key := emailChangeKey(claim.ID)
+
+	// Проверяем код (неверные попытки учитываются)
+	entry, err := s.checkCode(key, code)
+	if err != nil {
+		return err
+	}
+	if err = s.consumeCode(key); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return err
+	}
+
+	// Адрес мог занять другой пользователь, пока код ждал подтверждения
+	if err = s.repo.UpdateEmail(user.ID, entry.Email); err != nil {
+		return err
+	}
+
+	if err = s.sendEmailChangedEmail(user.Email, user.Locale, entry.Email); err != nil {
+		s.log.Error("Ошибка отправки уведомления о смене email:", err)
+	}
+
+	return nil
+}
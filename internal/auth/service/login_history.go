@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"net"
+)
+
+const (
+	defaultHistoryLimit = 20    // Размер страницы истории входов по умолчанию
+	maxHistoryLimit     = 100   // Максимальный размер страницы истории входов
+	maxHistoryPage      = 10000 // Максимальный номер страницы истории входов
+)
+
+var (
+	errInvalidIP   = errors.New("некорректный IP-адрес или подсеть")
+	errHistoryPage = fmt.Errorf("номер страницы истории входов не может быть больше %d", maxHistoryPage)
+)
+
+// GetLoginHistory возвращает историю входов текущего пользователя постранично.
+func (s *AuthService) GetLoginHistory(claim *entities.AccessClaim, page, limit int) (*entities.LoginHistoryPage, error) {
+	return s.loginHistoryPage(&entities.LoginHistoryFilter{UserID: claim.ID}, page, limit)
+}
+
+// SearchLoginHistory возвращает историю входов всех пользователей с фильтрами по пользователю,
+// IP-адресу (или подсети) и периоду. Используется администраторами.
+func (s *AuthService) SearchLoginHistory(filter *entities.LoginHistoryFilter, page, limit int) (*entities.LoginHistoryPage, error) {
+	if filter.IP != "" && net.ParseIP(filter.IP) == nil {
+		if _, _, err := net.ParseCIDR(filter.IP); err != nil {
+			return nil, errInvalidIP
+		}
+	}
+
+	return s.loginHistoryPage(filter, page, limit)
+}
+
+// loginHistoryPage приводит параметры пагинации к допустимым значениям и выбирает страницу истории.
+func (s *AuthService) loginHistoryPage(filter *entities.LoginHistoryFilter, page, limit int) (*entities.LoginHistoryPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	// Без ограничения смещение (page - 1) * limit может переполниться и стать отрицательным
+	if page > maxHistoryPage {
+		return nil, errHistoryPage
+	}
+	if limit < 1 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	items, total, err := s.repo.GetLoginHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.LoginHistoryPage{Items: items, Page: page, Limit: limit, Total: total}, nil
+}
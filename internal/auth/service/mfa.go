@@ -0,0 +1,312 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"os"
+	"strings"
+	"time"
+)
+
+const recoveryCodesCount = 10 // Количество выдаваемых кодов восстановления
+
+var (
+	ttlMFAChallenge      = time.Minute * 5 // Время, за которое нужно ввести второй фактор
+	ttlTOTPUsed          = time.Minute * 2 // Время хранения последнего использованного шага TOTP
+	errMFAAlreadyEnabled = errors.New("двухфакторная аутентификация уже включена")
+	errMFANotEnrolled    = errors.New("сначала начните подключение двухфакторной аутентификации")
+	errMFAChallenge      = errors.New("сессия входа не найдена или истекла, выполните вход заново")
+	errMFAWrongCode      = errors.New("неверный код двухфакторной аутентификации")
+)
+
+// mfaChallenge — первый шаг входа пройден (пароль верен), ожидается второй фактор.
+// Login — логин (email или телефон), по которому выполнялся первый шаг: ошибки второго фактора
+// учитываются в его счётчике неудачных попыток. Попытки ввода кода для челленджа считаются
+// отдельным счётчиком (см. takeCodeAttempt). Phone — телефон, если первый шаг пройден по SMS.
+type mfaChallenge struct {
+	UserID    int    `json:"userId"`
+	Login     string `json:"login"`
+	Phone     string `json:"phone,omitempty"`
+	IPAddress string `json:"ipAddress"`
+	UserAgent string `json:"userAgent"`
+}
+
+func mfaChallengeKey(token string) string {
+	return "mfa_challenge:" + utils.HashToken(token)
+}
+
+func totpUsedKey(userID int) string {
+	return fmt.Sprintf("mfa_totp_used:%d", userID)
+}
+
+// totpUseScript атомарно отмечает шаг TOTP (ARGV[1]) использованным: сохраняет его в KEYS[1] на ARGV[2] мс,
+// только если сохранённый шаг меньше. Возвращает 1, если шаг принят, и 0, если этот или более поздний код
+// уже был использован — в том числе параллельным запросом.
+var totpUseScript = redis.NewScript(`
+local last = tonumber(redis.call('GET', KEYS[1]))
+local step = tonumber(ARGV[1])
+if last and step <= last then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// EnrollTOTP начинает подключение 2FA: генерирует секрет и возвращает otpauth URI.
+// 2FA включается только после подтверждения кодом из приложения (VerifyTOTP).
+func (s *AuthService) EnrollTOTP(claim *entities.AccessClaim) (*entities.TOTPEnrollResponse, error) {
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		s.log.Error("ошибка при генерации секрета TOTP:", err)
+		return nil, errInternal
+	}
+
+	if err = s.repo.SetTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	issuer := os.Getenv("MFA_ISSUER")
+	if issuer == "" {
+		issuer = "Food Delivery"
+	}
+
+	return &entities.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// VerifyTOTP подтверждает подключение 2FA кодом из приложения, включает её и выдаёт коды восстановления.
+func (s *AuthService) VerifyTOTP(claim *entities.AccessClaim, code string) (*entities.RecoveryCodesResponse, error) {
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errMFAAlreadyEnabled
+	}
+
+	secret, err := s.repo.GetTOTPSecret(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errMFANotEnrolled
+	}
+
+	if err = s.checkTOTP(user.ID, secret, code); err != nil {
+		return nil, err
+	}
+
+	// Генерируем коды восстановления: пользователь видит их один раз, в базе хранятся только хеши
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		raw, err := utils.GenRandToken(5)
+		if err != nil {
+			s.log.Error("ошибка при генерации кода восстановления:", err)
+			return nil, errInternal
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = utils.HashToken(codes[i])
+	}
+
+	if err = s.repo.EnableTOTP(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &entities.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// SignInMFA завершает вход второго шага: проверяет код из приложения или код восстановления
+// и выдаёт токены так же, как обычный вход.
+func (s *AuthService) SignInMFA(request *entities.MFASignInRequest) (*entities.TokensResponse, error) {
+	if request.MFAToken == "" || (request.Code == "" && request.RecoveryCode == "") {
+		return nil, errInvalidData
+	}
+
+	key := mfaChallengeKey(request.MFAToken)
+
+	// Попытка учитывается атомарно до проверки кода: для одного челленджа проверяется не больше maxCodeAttempts кодов
+	last, err := s.takeCodeAttempt(key)
+	if errors.Is(err, errCodeNotFound) {
+		return nil, errMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, errMFAChallenge
+	}
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	var challenge mfaChallenge
+	if err = json.Unmarshal([]byte(result), &challenge); err != nil {
+		s.log.Error("Ошибка при декодировании данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	// Блокировки по логину и IP, а также блокировка ввода второго фактора для пользователя
+	// действуют и на втором шаге входа
+	err = s.checkSignInAllowed(challenge.Login, challenge.IPAddress)
+	if err == nil {
+		err = s.checkMFAAllowed(challenge.UserID)
+	}
+	if err != nil {
+		entry := &entities.LoginHistory{
+			UserID:    challenge.UserID,
+			Phone:     challenge.Phone,
+			IPAddress: challenge.IPAddress,
+			UserAgent: challenge.UserAgent,
+			Reason:    entities.LoginFailLocked,
+		}
+		if challenge.Phone == "" {
+			entry.Email = challenge.Login
+		}
+		s.saveLoginAttempt(entry)
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пользователя могли заблокировать после первого шага входа
+	if !entities.CanSignIn(user.Status) {
+		s.dropCode(key)
+		s.saveLoginAttempt(&entities.LoginHistory{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Phone:     challenge.Phone,
+			IPAddress: challenge.IPAddress,
+			UserAgent: challenge.UserAgent,
+			Reason:    entities.LoginFailBlocked,
+		})
+		return nil, errAccountUnavailable
+	}
+
+	err = s.checkSecondFactor(user.ID, request.Code, request.RecoveryCode)
+	if errors.Is(err, errMFAWrongCode) {
+		// Неверный код учитывается для логина, IP и пользователя, как неверный пароль
+		s.registerFailedMFA(challenge.Login, &entities.LoginHistory{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Phone:     challenge.Phone,
+			IPAddress: challenge.IPAddress,
+			UserAgent: challenge.UserAgent,
+			Reason:    entities.LoginFailWrongMFA,
+		})
+
+		// Попытки для этого челленджа исчерпаны
+		if last {
+			s.dropCode(key)
+			return nil, errTooManyAttempts
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Челлендж одноразовый
+	if err = s.consumeCode(key); err != nil {
+		if errors.Is(err, errCodeNotFound) {
+			return nil, errMFAChallenge
+		}
+		return nil, err
+	}
+
+	// Оба фактора пройдены: сбрасываем счётчики неудачных попыток
+	s.resetMFAFailures(challenge.Login, user.ID)
+
+	return s.startSession(user, challenge.Phone, challenge.IPAddress, challenge.UserAgent)
+}
+
+// createMFAChallenge сохраняет MFA-челлендж и возвращает его токен вместо пары токенов.
+// login — логин (email или телефон), по которому пройден первый шаг входа;
+// phone — телефон, если первый шаг пройден по SMS.
+func (s *AuthService) createMFAChallenge(user *entities.User, login, phone, ipAddress, userAgent string) (*entities.TokensResponse, error) {
+	// Пока ввод второго фактора заблокирован, новые челленджи не выдаются
+	if err := s.checkMFAAllowed(user.ID); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenRandToken(32)
+	if err != nil {
+		s.log.Error("ошибка при генерации MFA токена:", err)
+		return nil, errInternal
+	}
+
+	challengeJSON, err := json.Marshal(mfaChallenge{UserID: user.ID, Login: login, Phone: phone, IPAddress: ipAddress, UserAgent: userAgent})
+	if err != nil {
+		s.log.Error("не удалось сериализовать MFA-челлендж:", err)
+		return nil, errInternal
+	}
+
+	// В Redis хранится только хеш токена челленджа
+	if err = s.client.Set(ctx, mfaChallengeKey(token), challengeJSON, ttlMFAChallenge).Err(); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return nil, errInternal
+	}
+
+	return &entities.TokensResponse{MFARequired: true, MFAToken: token}, nil
+}
+
+// checkSecondFactor проверяет второй фактор: код из приложения или одноразовый код восстановления.
+func (s *AuthService) checkSecondFactor(userID int, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		used, err := s.repo.UseRecoveryCode(userID, utils.HashToken(strings.ToLower(strings.TrimSpace(recoveryCode))))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errMFAWrongCode
+		}
+		return nil
+	}
+
+	secret, err := s.repo.GetTOTPSecret(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.checkTOTP(userID, secret, code)
+}
+
+// checkTOTP проверяет код из приложения-аутентификатора и запрещает повторное использование одного и того же кода.
+func (s *AuthService) checkTOTP(userID int, secret, code string) error {
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return errMFAWrongCode
+	}
+
+	// Код, уже использованный для входа, повторно не принимается: проверка и отметка выполняются атомарно
+	accepted, err := totpUseScript.Run(ctx, s.client, []string{totpUsedKey(userID)}, step, ttlTOTPUsed.Milliseconds()).Int()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if accepted == 0 {
+		return errMFAWrongCode
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"food-delivery/pkg/utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTOTPCode вычисляет текущий код TOTP (RFC 6238: HMAC-SHA1, 6 цифр, период 30 секунд).
+func testTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1_000_000)
+}
+
+func TestCheckTOTPConcurrentReplay(t *testing.T) {
+	client := testRedis(t)
+	s := newTestOIDCService(t, newFakeOIDCRepo(), client, nil)
+
+	const userID = 42
+	client.Del(ctx, totpUsedKey(userID))
+	t.Cleanup(func() { client.Del(ctx, totpUsedKey(userID)) })
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := testTOTPCode(t, secret)
+
+	// Один и тот же код, отправленный параллельно, принимается ровно один раз
+	const requests = 20
+	var accepted, rejected int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.checkTOTP(userID, secret, code)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case errors.Is(err, errMFAWrongCode):
+				rejected++
+			default:
+				t.Errorf("checkTOTP: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 || rejected != requests-1 {
+		t.Errorf("код принят %d раз, отклонён %d раз; ожидалось 1 и %d", accepted, rejected, requests-1)
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/oidc"
+	"food-delivery/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+const (
+	maxOIDCFirstnameRunes    = 50 // Ограничение длины имени (users.firstname VARCHAR(50))
+	oidcUnusablePasswordSize = 32 // Размер случайного пароля для пользователей, созданных через провайдера
+)
+
+var (
+	ttlOIDCState            = time.Minute * 10 // Время, за которое нужно вернуться от провайдера
+	errOIDCProvider         = errors.New("неизвестный провайдер входа")
+	errOIDCState            = errors.New("сессия входа через провайдера не найдена или истекла, начните вход заново")
+	errOIDCEmailNotVerified = errors.New("email не подтверждён у провайдера, войдите по паролю")
+	errOIDCProviderFailed   = errors.New("не удалось выполнить вход через провайдера")
+)
+
+// oidcState — данные начатого входа через провайдера, ожидающие возврата пользователя на callback.
+type oidcState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"codeVerifier"`
+	Nonce        string `json:"nonce"`
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + utils.HashToken(state)
+}
+
+// StartOIDCSignIn начинает вход через внешнего провайдера: сохраняет state, nonce и PKCE verifier
+// и возвращает адрес страницы входа провайдера вместе с state для cookie.
+func (s *AuthService) StartOIDCSignIn(provider string) (string, string, error) {
+	client, ok := s.oidc[provider]
+	if !ok {
+		return "", "", errOIDCProvider
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		s.log.Error("ошибка при генерации state:", err)
+		return "", "", errInternal
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		s.log.Error("ошибка при генерации nonce:", err)
+		return "", "", errInternal
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		s.log.Error("ошибка при генерации code verifier:", err)
+		return "", "", errInternal
+	}
+
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		s.log.Error("Ошибка при обращении к OIDC провайдеру:", err)
+		return "", "", errOIDCProviderFailed
+	}
+
+	stateJSON, err := json.Marshal(oidcState{Provider: provider, CodeVerifier: verifier, Nonce: nonce})
+	if err != nil {
+		s.log.Error("не удалось сериализовать state:", err)
+		return "", "", errInternal
+	}
+
+	// В Redis хранится только хеш state, сам state уходит провайдеру и в cookie
+	if err = s.client.Set(ctx, oidcStateKey(state), stateJSON, ttlOIDCState).Err(); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return "", "", errInternal
+	}
+
+	return authURL, state, nil
+}
+
+// FinishOIDCSignIn завершает вход через провайдера: обменивает код на токены, проверяет ID токен,
+// находит или создаёт пользователя и выдаёт токены приложения так же, как SignIn.
+// Совпадение state из запроса и из cookie проверяется в обработчике.
+func (s *AuthService) FinishOIDCSignIn(provider, code, state, ipAddress, userAgent string) (*entities.TokensResponse, error) {
+	client, ok := s.oidc[provider]
+	if !ok {
+		return nil, errOIDCProvider
+	}
+	if code == "" || state == "" {
+		return nil, errInvalidData
+	}
+
+	// state одноразовый: удаляем его сразу при получении
+	stateJSON, err := s.client.GetDel(ctx, oidcStateKey(state)).Result()
+	if err == redis.Nil {
+		return nil, errOIDCState
+	}
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	var saved oidcState
+	if err = json.Unmarshal([]byte(stateJSON), &saved); err != nil {
+		s.log.Error("Ошибка при декодировании данных из Redis:", err)
+		return nil, errInternal
+	}
+	if saved.Provider != provider {
+		return nil, errOIDCState
+	}
+
+	tokens, err := client.Exchange(ctx, code, saved.CodeVerifier)
+	if err != nil {
+		s.log.Error("Ошибка при обращении к OIDC провайдеру:", err)
+		return nil, errOIDCProviderFailed
+	}
+
+	claims, err := client.VerifyIDToken(ctx, tokens.IDToken, saved.Nonce)
+	if err != nil {
+		s.log.Error("Ошибка проверки ID токена провайдера:", err)
+		return nil, errOIDCProviderFailed
+	}
+
+	userID, err := s.resolveOIDCUser(provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, errInternal
+	}
+
+	if !entities.CanSignIn(user.Status) {
+		s.saveLoginAttempt(&entities.LoginHistory{
+			UserID:    user.ID,
+			Email:     user.Email,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailBlocked,
+		})
+		return nil, errAccountUnavailable
+	}
+
+	// Провайдер заменяет только пароль, второй фактор по-прежнему требуется
+	if user.TOTPEnabled {
+		return s.createMFAChallenge(user, normalizeEmail(user.Email), "", ipAddress, userAgent)
+	}
+
+	return s.startSession(user, "", ipAddress, userAgent)
+}
+
+// resolveOIDCUser находит пользователя по аккаунту провайдера. Если привязки ещё нет, аккаунт
+// привязывается к пользователю с тем же email или создаётся новый пользователь.
+// Привязка по email выполняется только для подтверждённого у провайдера email, иначе
+// чужой аккаунт можно было бы захватить, указав у провайдера его адрес.
+func (s *AuthService) resolveOIDCUser(provider string, claims *oidc.IDTokenClaims) (int, error) {
+	userID, err := s.repo.GetUserIDByIdentity(provider, claims.Subject)
+	if err == nil {
+		return userID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return 0, errOIDCEmailNotVerified
+	}
+	// Провайдеры не гарантируют регистр email: приводим его к единому виду, чтобы не создать второй аккаунт
+	email := normalizeEmail(claims.Email)
+
+	existing, err := s.repo.GetUserByEmail(email)
+	if err == nil {
+		if err = s.repo.CreateIdentity(existing.ID, provider, claims.Subject, email); err != nil {
+			return 0, err
+		}
+		return existing.ID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// У нового пользователя нет пароля: сохраняем хеш случайной строки, которую никто не знает.
+	// Задать пароль можно через сброс пароля.
+	password, err := utils.GenRandToken(oidcUnusablePasswordSize)
+	if err != nil {
+		s.log.Error("ошибка при генерации пароля:", err)
+		return 0, errInternal
+	}
+	passwordHash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.log.Error("ошибка хеширования пароля:", err)
+		return 0, errInternal
+	}
+
+	return s.repo.CreateUserWithIdentity(&entities.User{
+		Firstname: oidcFirstname(claims),
+		Email:     email,
+		Password:  passwordHash,
+		Locale:    s.mailer.Locale(claims.Locale),
+	}, provider, claims.Subject)
+}
+
+// oidcFirstname выбирает имя пользователя из ID токена: given_name, name или часть email до «@».
+func oidcFirstname(claims *oidc.IDTokenClaims) string {
+	name := strings.TrimSpace(claims.GivenName)
+	if name == "" {
+		name = strings.TrimSpace(claims.Name)
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	if runes := []rune(name); len(runes) > maxOIDCFirstnameRunes {
+		name = string(runes[:maxOIDCFirstnameRunes])
+	}
+
+	return name
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/internal/auth/repository"
+	"food-delivery/pkg/hasher"
+	"food-delivery/pkg/logger"
+	"food-delivery/pkg/mailer"
+	"food-delivery/pkg/oidc"
+	"food-delivery/pkg/oidc/oidctest"
+	"food-delivery/pkg/passpolicy"
+	"food-delivery/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRedirectURL = "http://app.test/api/auth/oidc/mock/callback"
+
+// fakeOIDCRepo — репозиторий в памяти с методами, которые использует вход через провайдера.
+// Остальные методы интерфейса не реализованы и при вызове приводят к панике.
+type fakeOIDCRepo struct {
+	repository.AuthRepoInt
+
+	users      map[int]*entities.User
+	identities map[string]int // provider + "|" + subject -> user_id
+	created    int            // Количество пользователей, созданных через провайдера
+}
+
+func newFakeOIDCRepo(users ...*entities.User) *fakeOIDCRepo {
+	repo := &fakeOIDCRepo{users: make(map[int]*entities.User), identities: make(map[string]int)}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+
+	return repo
+}
+
+func (r *fakeOIDCRepo) GetUserIDByIdentity(provider, subject string) (int, error) {
+	userID, ok := r.identities[provider+"|"+subject]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	return userID, nil
+}
+
+// GetUserByEmail, как и запрос в базе данных, ищет email без учёта регистра.
+func (r *fakeOIDCRepo) GetUserByEmail(email string) (*entities.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeOIDCRepo) GetUserByID(userID int) (*entities.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (r *fakeOIDCRepo) CreateIdentity(userID int, provider, subject, email string) error {
+	r.identities[provider+"|"+subject] = userID
+	return nil
+}
+
+func (r *fakeOIDCRepo) CreateUserWithIdentity(user *entities.User, provider, subject string) (int, error) {
+	r.created++
+
+	created := *user
+	created.ID = 1000 + r.created
+	created.Status = entities.UserStatusActive
+	r.users[created.ID] = &created
+	r.identities[provider+"|"+subject] = created.ID
+
+	return created.ID, nil
+}
+
+func newTestOIDCService(t *testing.T, repo repository.AuthRepoInt, client *redis.Client, clients map[string]*oidc.Client) *AuthService {
+	t.Helper()
+
+	log, err := logger.NewLogger(filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passwordHasher, err := hasher.NewPasswordHasher(hasher.Config{Algorithm: hasher.AlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderer, err := mailer.NewRenderer("", "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := passpolicy.NewPolicy(passpolicy.DefaultMinScore, nil)
+
+	return NewAuthService(repo, client, nil, clients, passwordHasher, policy, mailer.NewMailer(mailer.SMTPConfig{}, renderer, log), log)
+}
+
+func providerClaims(subject, email string, verified bool) *oidc.IDTokenClaims {
+	claims := &oidc.IDTokenClaims{
+		Email:            email,
+		GivenName:        "Иван",
+		Locale:           "en",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}
+	if verified {
+		claims.EmailVerified = true
+	}
+
+	return claims
+}
+
+func TestResolveOIDCUser(t *testing.T) {
+	existing := &entities.User{ID: 1, Email: "user@example.com", Status: entities.UserStatusActive}
+
+	t.Run("привязка к пользователю с тем же email без учёта регистра", func(t *testing.T) {
+		repo := newFakeOIDCRepo(existing)
+		s := newTestOIDCService(t, repo, nil, nil)
+
+		userID, err := s.resolveOIDCUser("mock", providerClaims("sub-1", "  User@Example.COM ", true))
+		if err != nil {
+			t.Fatalf("resolveOIDCUser: %v", err)
+		}
+		if userID != existing.ID || repo.created != 0 {
+			t.Fatalf("userID = %d, создано пользователей: %d; ожидалась привязка к пользователю %d", userID, repo.created, existing.ID)
+		}
+
+		// Повторный вход находит пользователя по привязке
+		userID, err = s.resolveOIDCUser("mock", providerClaims("sub-1", "other@example.com", true))
+		if err != nil || userID != existing.ID {
+			t.Fatalf("повторный вход: userID = %d, ошибка %v", userID, err)
+		}
+	})
+
+	t.Run("создание нового пользователя", func(t *testing.T) {
+		repo := newFakeOIDCRepo(existing)
+		s := newTestOIDCService(t, repo, nil, nil)
+
+		userID, err := s.resolveOIDCUser("mock", providerClaims("sub-2", "New.User@Example.com", true))
+		if err != nil {
+			t.Fatalf("resolveOIDCUser: %v", err)
+		}
+		if repo.created != 1 {
+			t.Fatalf("создано пользователей: %d, ожидался 1", repo.created)
+		}
+
+		user := repo.users[userID]
+		if user.Email != "new.user@example.com" {
+			t.Errorf("email нового пользователя %q, ожидался приведённый к нижнему регистру", user.Email)
+		}
+		if user.Firstname != "Иван" || user.Locale != "en" {
+			t.Errorf("имя %q и язык %q не взяты из ID токена", user.Firstname, user.Locale)
+		}
+		if user.Password == "" || s.hasher.Compare(user.Password, "") == nil {
+			t.Error("у нового пользователя должен быть хеш случайного пароля")
+		}
+
+		// Второй вход тем же аккаунтом провайдера не создаёт ещё одного пользователя
+		again, err := s.resolveOIDCUser("mock", providerClaims("sub-2", "new.user@example.com", true))
+		if err != nil || again != userID || repo.created != 1 {
+			t.Fatalf("повторный вход: userID = %d (ожидался %d), создано %d, ошибка %v", again, userID, repo.created, err)
+		}
+	})
+
+	t.Run("неподтверждённый email не привязывается", func(t *testing.T) {
+		repo := newFakeOIDCRepo(existing)
+		s := newTestOIDCService(t, repo, nil, nil)
+
+		_, err := s.resolveOIDCUser("mock", providerClaims("sub-3", "user@example.com", false))
+		if !errors.Is(err, errOIDCEmailNotVerified) {
+			t.Fatalf("ошибка %v, ожидалась errOIDCEmailNotVerified", err)
+		}
+		if len(repo.identities) != 0 || repo.created != 0 {
+			t.Error("аккаунт провайдера с неподтверждённым email не должен привязываться или создавать пользователя")
+		}
+	})
+}
+
+// testRedis возвращает клиента Redis из REDIS_TEST_ADDR или пропускает тест, если Redis не настроен.
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR не задан, тест с Redis пропущен")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Redis %s недоступен: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestOIDCSignInState(t *testing.T) {
+	client := testRedis(t)
+
+	t.Setenv("TOKEN_HASH_KEY", strings.Repeat("k", 32))
+	if err := utils.LoadTokenHashKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := oidctest.NewProvider("food-delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+	provider.SetUser(*providerClaims("sub-1", "user@example.com", true))
+
+	// У пользователя включена 2FA: успешный вход заканчивается MFA-челленджем без выпуска токенов приложения
+	user := &entities.User{ID: 1, Email: "user@example.com", Status: entities.UserStatusActive, TOTPEnabled: true}
+	s := newTestOIDCService(t, newFakeOIDCRepo(user), client, map[string]*oidc.Client{
+		"mock":  provider.Client("mock", testRedirectURL),
+		"other": provider.Client("other", testRedirectURL),
+	})
+
+	start := func() (string, string) {
+		authURL, state, err := s.StartOIDCSignIn("mock")
+		if err != nil {
+			t.Fatalf("StartOIDCSignIn: %v", err)
+		}
+
+		code, returnedState, err := provider.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		if returnedState != state {
+			t.Fatalf("провайдер вернул state %q, ожидался %q", returnedState, state)
+		}
+
+		return code, state
+	}
+
+	code, state := start()
+	tokens, err := s.FinishOIDCSignIn("mock", code, state, "203.0.113.1", "test")
+	if err != nil {
+		t.Fatalf("FinishOIDCSignIn: %v", err)
+	}
+	if !tokens.MFARequired {
+		t.Error("ожидался MFA-челлендж для пользователя с 2FA")
+	}
+
+	// state одноразовый
+	if _, err = s.FinishOIDCSignIn("mock", code, state, "203.0.113.1", "test"); !errors.Is(err, errOIDCState) {
+		t.Errorf("повторное использование state: ошибка %v, ожидалась errOIDCState", err)
+	}
+
+	// Неизвестный state
+	if _, err = s.FinishOIDCSignIn("mock", code, "unknown-state", "203.0.113.1", "test"); !errors.Is(err, errOIDCState) {
+		t.Errorf("неизвестный state: ошибка %v, ожидалась errOIDCState", err)
+	}
+
+	// state, выданный для одного провайдера, не принимается на callback другого
+	code, state = start()
+	if _, err = s.FinishOIDCSignIn("other", code, state, "203.0.113.1", "test"); !errors.Is(err, errOIDCState) {
+		t.Errorf("state другого провайдера: ошибка %v, ожидалась errOIDCState", err)
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/passpolicy"
+	"food-delivery/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const maxReauthAttempts = 5 // Количество попыток подтверждения паролем за окно reauthWindow
+
+var (
+	ttlResetCode      = time.Minute * 15 // Время жизни кода сброса пароля
+	ttlResetThrottle  = time.Minute      // Минимальный интервал между запросами кода на один email
+	reauthWindow      = time.Minute * 15 // Окно, в течение которого считаются попытки подтверждения паролем
+	errResetThrottled = errors.New("код уже отправлен, повторите запрос через минуту")
+	errWrongPassword  = errors.New("неверный текущий пароль")
+	errMFARequired    = errors.New("требуется код двухфакторной аутентификации")
+	errReauthLocked   = errors.New("слишком много попыток подтверждения паролем, повторите через 15 минут")
+)
+
+// reauthAttemptScript атомарно увеличивает счётчик попыток подтверждения паролем (KEYS[1]);
+// окно (ARGV[1], мс) отсчитывается от первой попытки.
+var reauthAttemptScript = redis.NewScript(`
+local attempt = redis.call('INCR', KEYS[1])
+if attempt == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return attempt
+`)
+
+func reauthAttemptsKey(userID int) string {
+	return fmt.Sprintf("reauth_attempts:%d", userID)
+}
+
+// Ключи кода сброса пароля строятся по email, приведённому к нижнему регистру: пользователь ищется
+// без учёта регистра, и варианты написания одного адреса не должны получать отдельные коды и интервалы.
+func passwordResetKey(email string) string {
+	return "password_reset:" + normalizeEmail(email)
+}
+
+func passwordResetThrottleKey(email string) string {
+	return "password_reset_throttle:" + normalizeEmail(email)
+}
+
+// ForgotPassword отправляет на email одноразовый код для сброса пароля.
+// Чтобы по ответу нельзя было узнать, зарегистрирован ли email, для неизвестного адреса ошибка не возвращается.
+func (s *AuthService) ForgotPassword(email string) error {
+	if normalizeEmail(email) == "" {
+		return errInvalidData
+	}
+
+	// Не даём засыпать пользователя письмами: не чаще одного кода в минуту
+	allowed, err := s.client.SetNX(ctx, passwordResetThrottleKey(email), 1, ttlResetThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !allowed {
+		return errResetThrottled
+	}
+
+	user, err := s.repo.GetUserByEmail(email)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Пользователям, которым вход недоступен (заблокированным, приостановленным, удалённым), сброс пароля не нужен
+	if !entities.CanSignIn(user.Status) {
+		return nil
+	}
+
+	// Новый код заменяет ранее отправленный
+	code := utils.GenRandCode()
+	if err = s.saveCode(passwordResetKey(email), &codeEntry{UserID: user.ID, Code: code}, ttlResetCode); err != nil {
+		return err
+	}
+
+	// Отправляем (ассинхронно) код сброса пароля на email пользователя.
+	if err = s.sendPasswordResetEmail(user.Email, user.Locale, code); err != nil {
+		s.log.Error("Ошибка отправки кода сброса пароля на email:", err)
+	}
+
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль по коду из письма и завершает все сессии пользователя.
+func (s *AuthService) ResetPassword(request *entities.ResetPasswordRequest) error {
+	if request.Email == "" || request.Code == "" {
+		return errInvalidData
+	}
+
+	// Проверяем код (неверные попытки учитываются)
+	entry, err := s.checkCode(passwordResetKey(request.Email), request.Code)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(request.Email)
+	if err != nil || user.ID != entry.UserID {
+		return errCodeNotFound
+	}
+
+	// Новый пароль должен соответствовать тем же требованиям, что и при регистрации
+	user.Password = request.Password
+	if err = utils.ValidateUserForRegister(user); err != nil {
+		s.log.Error("невалидные данные:", err)
+		return err
+	}
+	if err = s.checkPasswordPolicy(user); err != nil {
+		return err
+	}
+
+	// Код одноразовый: удаляем его до смены пароля
+	if err = s.consumeCode(passwordResetKey(request.Email)); err != nil {
+		return err
+	}
+
+	return s.setPassword(user.ID, request.Password, "")
+}
+
+// ChangePassword меняет пароль авторизованного пользователя после проверки текущего пароля
+// (и второго фактора, если включена 2FA). Сессия, из которой выполнен запрос, сохраняется,
+// остальные сессии завершаются.
+func (s *AuthService) ChangePassword(claim *entities.AccessClaim, request *entities.ChangePasswordRequest) error {
+	user, err := s.repo.GetUserByID(claim.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.reauthenticate(user, request.CurrentPassword, request.Code, request.RecoveryCode); err != nil {
+		return err
+	}
+
+	// Новый пароль должен соответствовать тем же требованиям, что и при регистрации
+	user.Password = request.NewPassword
+	if err = utils.ValidateUserForRegister(user); err != nil {
+		s.log.Error("невалидные данные:", err)
+		return err
+	}
+	if err = s.checkPasswordPolicy(user); err != nil {
+		return err
+	}
+
+	if err = s.setPassword(user.ID, request.NewPassword, claim.SessionID); err != nil {
+		return err
+	}
+
+	// Уведомляем владельца аккаунта о смене пароля
+	if err = s.sendPasswordChangedEmail(user.Email, user.Locale); err != nil {
+		s.log.Error("Ошибка отправки уведомления о смене пароля:", err)
+	}
+
+	return nil
+}
+
+// reauthenticate повторно подтверждает личность пользователя перед изменением данных для входа или удалением аккаунта:
+// проверяет текущий пароль и, если включена 2FA, код из приложения или код восстановления.
+// Попытки ограничены maxReauthAttempts за reauthWindow на пользователя, чтобы владелец access токена
+// не мог подбирать пароль; неверный код второго фактора учитывается в счётчике пользователя так же, как при входе.
+func (s *AuthService) reauthenticate(user *entities.User, password, code, recoveryCode string) error {
+	if err := s.takeReauthAttempt(user.ID); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.repo.GetPasswordHash(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.hasher.Compare(passwordHash, password); err != nil {
+		s.log.Error("Ошибка проверки подленности пароля", err)
+		return errWrongPassword
+	}
+
+	if user.TOTPEnabled {
+		if code == "" && recoveryCode == "" {
+			return errMFARequired
+		}
+		if err = s.checkMFAAllowed(user.ID); err != nil {
+			return err
+		}
+
+		err = s.checkSecondFactor(user.ID, code, recoveryCode)
+		if errors.Is(err, errMFAWrongCode) {
+			s.countFailure("mfa", strconv.Itoa(user.ID), signInMFALockAt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Личность подтверждена: счётчик попыток начинается заново
+	if err = s.client.Del(ctx, reauthAttemptsKey(user.ID)).Err(); err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+	}
+
+	return nil
+}
+
+// takeReauthAttempt учитывает попытку подтверждения паролем ещё до проверки пароля,
+// поэтому параллельные запросы не могут проверить больше maxReauthAttempts вариантов.
+func (s *AuthService) takeReauthAttempt(userID int) error {
+	attempt, err := reauthAttemptScript.Run(ctx, s.client, []string{reauthAttemptsKey(userID)}, reauthWindow.Milliseconds()).Int()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if attempt > maxReauthAttempts {
+		return errReauthLocked
+	}
+
+	return nil
+}
+
+// checkPasswordPolicy проверяет стойкость пароля пользователя и его отсутствие в базе утёкших паролей.
+// Пароль не должен быть основан на email, имени или телефоне пользователя.
+func (s *AuthService) checkPasswordPolicy(user *entities.User) error {
+	err := s.policy.Check(user.Password, user.Email, user.Firstname, user.Phone)
+	if errors.Is(err, passpolicy.ErrCorpusUnavailable) {
+		s.log.Error("ошибка проверки пароля:", err)
+		return errInternal
+	}
+	if err != nil {
+		s.log.Error("пароль не прошёл проверку:", err)
+		return err
+	}
+
+	return nil
+}
+
+// upgradePasswordHash пересчитывает хеш пароля текущим алгоритмом, если сохранённый хеш посчитан
+// устаревшим алгоритмом или с устаревшими параметрами. Вызывается после успешной проверки пароля,
+// ошибка не прерывает вход.
+func (s *AuthService) upgradePasswordHash(userID int, password, passwordHash string) {
+	if !s.hasher.NeedsRehash(passwordHash) {
+		return
+	}
+
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.log.Error("ошибка хеширования пароля:", err)
+		return
+	}
+
+	if err = s.repo.UpgradePasswordHash(userID, passwordHash, newHash); err != nil {
+		s.log.Error("не удалось обновить хеш пароля:", err)
+	}
+}
+
+// setPassword хеширует и сохраняет новый пароль, после чего завершает сессии пользователя
+// (кроме keepSessionID, если он указан), отменяет начатую смену email и отзывает его access токены.
+func (s *AuthService) setPassword(userID int, password, keepSessionID string) error {
+	// Хешируем пароль пользователя для безопасности.
+	passwordHash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.log.Error("ошибка хеширования пароля:", err)
+		return errInternal
+	}
+
+	err = s.repo.UpdateRecord("users", map[string]interface{}{
+		"password_hash":           passwordHash,
+		"password_reset_required": false,
+	}, userID)
+	if err != nil {
+		return err
+	}
+
+	// Завершаем сессии в таблице tokens
+	if keepSessionID == "" {
+		err = s.repo.DeleteTokenByID(userID)
+	} else {
+		err = s.repo.DeleteOtherSessions(userID, keepSessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Смена пароля отменяет начатую смену email: её мог начать тот, кто знал прежний пароль
+	s.dropCode(emailChangeKey(userID))
+
+	return s.RevokeUserAccess(userID)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"testing"
+)
+
+// fakeAccountRepo дополняет fakeOIDCRepo методами, которые используют смена и сброс пароля.
+type fakeAccountRepo struct {
+	*fakeOIDCRepo
+
+	terminated int // Сколько раз завершались все сессии пользователя
+}
+
+func newFakeAccountRepo(users ...*entities.User) *fakeAccountRepo {
+	return &fakeAccountRepo{fakeOIDCRepo: newFakeOIDCRepo(users...)}
+}
+
+func (r *fakeAccountRepo) GetPasswordHash(userID int) (string, error) {
+	user, err := r.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	return user.Password, nil
+}
+
+func (r *fakeAccountRepo) UpdateRecord(table string, fields map[string]interface{}, id int) error {
+	user, err := r.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if hash, ok := fields["password_hash"].(string); ok {
+		user.Password = hash
+	}
+
+	return nil
+}
+
+func (r *fakeAccountRepo) DeleteTokenByID(userID int) error {
+	r.terminated++
+	return nil
+}
+
+func (r *fakeAccountRepo) DeleteOtherSessions(userID int, keepSessionID string) error {
+	r.terminated++
+	return nil
+}
+
+// newTestUser создаёт активного пользователя с указанным паролем.
+func newTestUser(t *testing.T, s *AuthService, id int, email, password string) *entities.User {
+	t.Helper()
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &entities.User{ID: id, Email: email, Firstname: "Иван", Phone: "+79161234567", Password: hash, Status: entities.UserStatusActive, Locale: "ru"}
+}
+
+// storedCode читает код, сохранённый под ключом key.
+func storedCode(t *testing.T, s *AuthService, key string) string {
+	t.Helper()
+
+	value, err := s.client.Get(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("код %s не найден: %v", key, err)
+	}
+
+	var entry codeEntry
+	if err = json.Unmarshal([]byte(value), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	return entry.Code
+}
+
+func TestPasswordResetKeysIgnoreCase(t *testing.T) {
+	for _, email := range []string{"User@Example.com", " user@example.com ", "USER@EXAMPLE.COM"} {
+		if got := passwordResetKey(email); got != passwordResetKey("user@example.com") {
+			t.Errorf("passwordResetKey(%q) = %q", email, got)
+		}
+		if got := passwordResetThrottleKey(email); got != passwordResetThrottleKey("user@example.com") {
+			t.Errorf("passwordResetThrottleKey(%q) = %q", email, got)
+		}
+	}
+}
+
+func TestPasswordResetMixedCaseEmail(t *testing.T) {
+	client := testRedis(t)
+
+	repo := newFakeAccountRepo()
+	s := newTestOIDCService(t, repo, client, nil)
+	user := newTestUser(t, s, 1, "user@example.com", "correct horse battery staple")
+	repo.users[user.ID] = user
+	t.Cleanup(func() {
+		client.Del(ctx, passwordResetKey(user.Email), passwordResetThrottleKey(user.Email), codeAttemptsKey(passwordResetKey(user.Email)))
+	})
+
+	if err := s.ForgotPassword("User@Example.com"); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+
+	// Другой вариант написания того же адреса не обходит интервал между письмами
+	if err := s.ForgotPassword("USER@example.COM"); !errors.Is(err, errResetThrottled) {
+		t.Errorf("повторный запрос в другом регистре: ошибка %v, ожидалась errResetThrottled", err)
+	}
+
+	// Код, запрошенный для одного написания адреса, принимается для другого
+	err := s.ResetPassword(&entities.ResetPasswordRequest{
+		Email:    "user@EXAMPLE.com",
+		Code:     storedCode(t, s, passwordResetKey("user@example.com")),
+		Password: "purple monkey dishwasher lamp",
+	})
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if s.hasher.Compare(user.Password, "purple monkey dishwasher lamp") != nil {
+		t.Error("пароль не изменён")
+	}
+}
+
+func TestChangePasswordAttemptLimit(t *testing.T) {
+	client := testRedis(t)
+
+	repo := newFakeAccountRepo()
+	s := newTestOIDCService(t, repo, client, nil)
+	user := newTestUser(t, s, 2, "limit@example.com", "correct horse battery staple")
+	repo.users[user.ID] = user
+	t.Cleanup(func() { client.Del(ctx, reauthAttemptsKey(user.ID)) })
+
+	claim := &entities.AccessClaim{ID: user.ID, SessionID: "session-1"}
+	for i := 0; i < maxReauthAttempts; i++ {
+		err := s.ChangePassword(claim, &entities.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "purple monkey dishwasher lamp"})
+		if !errors.Is(err, errWrongPassword) {
+			t.Fatalf("попытка %d: ошибка %v, ожидалась errWrongPassword", i+1, err)
+		}
+	}
+
+	// После исчерпания попыток не принимается даже верный пароль
+	err := s.ChangePassword(claim, &entities.ChangePasswordRequest{CurrentPassword: "correct horse battery staple", NewPassword: "purple monkey dishwasher lamp"})
+	if !errors.Is(err, errReauthLocked) {
+		t.Errorf("ошибка %v, ожидалась errReauthLocked", err)
+	}
+	if s.hasher.Compare(user.Password, "correct horse battery staple") != nil {
+		t.Error("пароль изменён после исчерпания попыток")
+	}
+}
+
+func TestChangePasswordRequiresSecondFactor(t *testing.T) {
+	client := testRedis(t)
+
+	repo := newFakeAccountRepo()
+	s := newTestOIDCService(t, repo, client, nil)
+	user := newTestUser(t, s, 3, "mfa@example.com", "correct horse battery staple")
+	user.TOTPEnabled = true
+	repo.users[user.ID] = user
+	t.Cleanup(func() { client.Del(ctx, reauthAttemptsKey(user.ID)) })
+
+	err := s.ChangePassword(&entities.AccessClaim{ID: user.ID}, &entities.ChangePasswordRequest{
+		CurrentPassword: "correct horse battery staple",
+		NewPassword:     "purple monkey dishwasher lamp",
+	})
+	if !errors.Is(err, errMFARequired) {
+		t.Errorf("ошибка %v, ожидалась errMFARequired", err)
+	}
+}
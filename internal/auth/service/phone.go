@@ -0,0 +1,236 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"time"
+)
+
+var (
+	ttlPhoneSignIn         = time.Minute * 5 // Время жизни кода для входа по номеру телефона
+	ttlPhoneSignInThrottle = time.Minute     // Минимальный интервал между отправками кода для входа
+	errPhoneSignInThrottle = errors.New("код уже отправлен, повторите запрос позже")
+	errPhoneVerified       = errors.New("номер телефона уже подтверждён")
+)
+
+func phoneVerificationKey(email string) string {
+	return "phone_verification:" + normalizeEmail(email)
+}
+
+func phoneResendThrottleKey(email string) string {
+	return "phone_verification_resend:" + normalizeEmail(email)
+}
+
+func phoneSignInKey(phone string) string {
+	return "phone_signin:" + phone
+}
+
+func phoneSignInThrottleKey(phone string) string {
+	return "phone_signin_throttle:" + phone
+}
+
+// sendPhoneVerificationCode отправляет по SMS код подтверждения номера телефона, указанного при регистрации.
+// Код живёт столько же, сколько незавершённая регистрация, и может быть подтверждён как до, так и после
+// подтверждения email.
+func (s *AuthService) sendPhoneVerificationCode(email, phone string) error {
+	code := utils.GenRandCode()
+	if err := s.saveCode(phoneVerificationKey(email), &codeEntry{Code: code}, ttlRegistration); err != nil {
+		return err
+	}
+
+	if err := s.sms.Send(phone, fmt.Sprintf("Код подтверждения номера: %s", code)); err != nil {
+		s.log.Error("Ошибка отправки кода подтверждения по SMS:", err)
+	}
+
+	return nil
+}
+
+// ResendPhoneCode повторно отправляет по SMS код подтверждения номера телефона для незавершённой регистрации.
+// Как и для кода из письма, нужен токен регистрации; отправка не чаще раза в минуту и не больше maxCodeResends раз.
+func (s *AuthService) ResendPhoneCode(email, registrationToken string) error {
+	if email == "" {
+		return errInvalidData
+	}
+
+	reg, err := s.getOwnRegistration(email, registrationToken)
+	if err != nil {
+		return err
+	}
+	if reg.PhoneVerified {
+		return errPhoneVerified
+	}
+
+	// Не чаще одной SMS в минуту для одной регистрации
+	allowed, err := s.client.SetNX(ctx, phoneResendThrottleKey(email), 1, ttlResendThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !allowed {
+		return errResendThrottled
+	}
+
+	if reg.PhoneResends >= maxCodeResends {
+		return errTooManyResends
+	}
+
+	reg.PhoneResends++
+	if err = s.updateRegistration(reg); err != nil {
+		return err
+	}
+
+	// Новый код заменяет старый и сбрасывает счётчик попыток
+	return s.sendPhoneVerificationCode(reg.User.Email, reg.User.Phone)
+}
+
+// ConfirmPhone подтверждает номер телефона кодом из SMS.
+// Если регистрация ещё не завершена, отметка сохраняется в ней, иначе обновляется пользователь в базе данных.
+func (s *AuthService) ConfirmPhone(email, code string) error {
+	if email == "" || code == "" {
+		return errInvalidData
+	}
+
+	key := phoneVerificationKey(email)
+
+	// Проверяем код (неверные попытки учитываются)
+	if _, err := s.checkCode(key, code); err != nil {
+		return err
+	}
+	if err := s.consumeCode(key); err != nil {
+		return err
+	}
+
+	// Регистрация ещё ожидает подтверждения email
+	reg, err := s.getRegistration(email)
+	if err == nil {
+		reg.PhoneVerified = true
+		// Если регистрация успела завершиться параллельно, обновляем пользователя в базе данных
+		if err = s.updateRegistration(reg); err != errRegNotFound {
+			return err
+		}
+	}
+	if err != errRegNotFound {
+		return err
+	}
+
+	// Регистрация уже завершена
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return errRegNotFound
+	}
+
+	return s.repo.VerifyPhone(user.ID)
+}
+
+// RequestPhoneSignInCode отправляет по SMS одноразовый код для входа по номеру телефона.
+// Код отправляется только на подтверждённые номера активных пользователей, но ответ не зависит
+// от того, зарегистрирован ли номер, чтобы по нему нельзя было перебирать клиентов.
+func (s *AuthService) RequestPhoneSignInCode(phone string) error {
+	phone, err := utils.NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
+
+	// Ограничиваем частоту отправки SMS на один номер
+	ok, err := s.client.SetNX(ctx, phoneSignInThrottleKey(phone), 1, ttlPhoneSignInThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !ok {
+		return errPhoneSignInThrottle
+	}
+
+	user, err := s.repo.GetUserByPhone(phone)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.PhoneVerified || !entities.CanSignIn(user.Status) {
+		return nil
+	}
+
+	code := utils.GenRandCode()
+	if err = s.saveCode(phoneSignInKey(phone), &codeEntry{UserID: user.ID, Code: code}, ttlPhoneSignIn); err != nil {
+		return err
+	}
+
+	if err = s.sms.Send(phone, fmt.Sprintf("Код для входа: %s", code)); err != nil {
+		s.log.Error("Ошибка отправки кода для входа по SMS:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// SignInByPhone выполняет вход по номеру телефона и коду из SMS и выдаёт те же токены, что и SignIn.
+func (s *AuthService) SignInByPhone(request *entities.PhoneSignInRequest, ipAddress, userAgent string) (*entities.TokensResponse, error) {
+	phone, err := utils.NormalizePhone(request.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if request.Code == "" {
+		return nil, errInvalidData
+	}
+
+	// Защита от подбора кода: те же блокировки и задержки, что и при входе по паролю
+	if err = s.checkSignInAllowed(phone, ipAddress); err != nil {
+		s.saveLoginAttempt(&entities.LoginHistory{
+			Phone:     phone,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailLocked,
+		})
+		return nil, err
+	}
+
+	key := phoneSignInKey(phone)
+
+	// Проверяем код (неверные попытки учитываются)
+	entry, err := s.checkCode(key, request.Code)
+	if err != nil {
+		s.registerFailedSignIn(phone, &entities.LoginHistory{
+			Phone:     phone,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailWrongCode,
+		})
+		return nil, err
+	}
+	if err = s.consumeCode(key); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(entry.UserID)
+	if err != nil {
+		return nil, errInternal
+	}
+
+	// Пользователя могли заблокировать после отправки кода
+	if !entities.CanSignIn(user.Status) {
+		s.saveLoginAttempt(&entities.LoginHistory{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Phone:     phone,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Reason:    entities.LoginFailBlocked,
+		})
+		return nil, errAccountUnavailable
+	}
+
+	// Вход по SMS заменяет только пароль, второй фактор по-прежнему требуется
+	if user.TOTPEnabled {
+		return s.createMFAChallenge(user, phone, phone, ipAddress, userAgent)
+	}
+
+	// Код верен: сбрасываем счётчик неудачных попыток
+	s.resetSignInFailures(phone)
+
+	return s.startSession(user, phone, ipAddress, userAgent)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+const maxCodeResends = 5 // Максимальное количество повторных отправок кода для одной регистрации
+
+var (
+	ttlRegistration    = time.Hour * 24 // Время, в течение которого можно продолжить незавершённую регистрацию
+	ttlResendThrottle  = time.Minute    // Минимальный интервал между повторными отправками кода
+	errRegNotFound     = errors.New("регистрация не найдена или устарела, зарегистрируйтесь заново")
+	errCodeExpired     = errors.New("срок действия кода истек, запросите новый код")
+	errResendThrottled = errors.New("код уже отправлен, повторите запрос через минуту")
+	errTooManyResends  = errors.New("превышено количество повторных отправок кода, зарегистрируйтесь заново")
+	errTooManyRegCodes = errors.New("превышено количество попыток ввода кода, зарегистрируйтесь заново")
+)
+
+// pendingRegistration — незавершённая регистрация, ожидающая подтверждения email.
+// Хранится в Redis дольше самого кода, поэтому после истечения кода регистрацию можно продолжить,
+// запросив новый код.
+type pendingRegistration struct {
+	User          entities.User `json:"user"`          // Данные пользователя (с хешем пароля)
+	Code          string        `json:"code"`          // Текущий код подтверждения
+	CodeExpiresAt time.Time     `json:"codeExpiresAt"` // Время истечения текущего кода
+	Resends       int           `json:"resends"`       // Количество повторных отправок кода
+	PhoneResends  int           `json:"phoneResends"`  // Количество повторных отправок кода по SMS
+	PhoneVerified bool          `json:"phoneVerified"` // Номер телефона подтверждён кодом из SMS
+	TokenHash     string        `json:"tokenHash"`     // Хеш токена регистрации, выданного тому, кто её начал
+}
+
+// Все ключи регистрации находятся в пространстве имён конкретного email, поэтому
+// одновременные регистрации не пересекаются, а подбирать код можно только для одного адреса.
+
+func registrationKey(email string) string {
+	return "registration:" + normalizeEmail(email)
+}
+
+func resendThrottleKey(email string) string {
+	return "registration_resend:" + normalizeEmail(email)
+}
+
+// normalizeEmail приводит email к единому виду для использования в ключах Redis.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ResendCode выпускает новый код подтверждения для незавершённой регистрации и продлевает её.
+func (s *AuthService) ResendCode(email, registrationToken string) error {
+	if email == "" {
+		return errInvalidData
+	}
+
+	reg, err := s.getOwnRegistration(email, registrationToken)
+	if err != nil {
+		return err
+	}
+
+	// Не чаще одной отправки в минуту на один email
+	if err = s.takeResendSlot(email); err != nil {
+		return err
+	}
+
+	if reg.Resends >= maxCodeResends {
+		return errTooManyResends
+	}
+
+	// Новый код заменяет старый
+	reg.Resends++
+
+	return s.issueConfirmationCode(reg)
+}
+
+// takeResendSlot разрешает отправку письма с кодом регистрации не чаще одного раза в ttlResendThrottle на email.
+func (s *AuthService) takeResendSlot(email string) error {
+	allowed, err := s.client.SetNX(ctx, resendThrottleKey(email), 1, ttlResendThrottle).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !allowed {
+		return errResendThrottled
+	}
+
+	return nil
+}
+
+// createRegistration сохраняет новую регистрацию с кодом подтверждения и отправляет код на email.
+// Незавершённая регистрация с тем же email заменяется вместе со счётчиком попыток ввода кода.
+func (s *AuthService) createRegistration(reg *pendingRegistration) error {
+	newConfirmationCode(reg)
+
+	regJSON, err := json.Marshal(reg)
+	if err != nil {
+		s.log.Error("не удалось сериализовать данные пользователя:", err)
+		return errInternal
+	}
+
+	key := registrationKey(reg.User.Email)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, regJSON, ttlRegistration)
+		pipe.Del(ctx, codeAttemptsKey(key))
+		return nil
+	})
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+
+	s.sendRegistrationCode(reg)
+	return nil
+}
+
+// issueConfirmationCode генерирует новый код для регистрации, сохраняет её в Redis
+// (заново отсчитывая время жизни) и отправляет код на email пользователя.
+func (s *AuthService) issueConfirmationCode(reg *pendingRegistration) error {
+	newConfirmationCode(reg)
+
+	if err := s.saveRegistration(reg, ttlRegistration); err != nil {
+		return err
+	}
+
+	s.sendRegistrationCode(reg)
+	return nil
+}
+
+// newConfirmationCode генерирует случайный код подтверждения, который будет отправлен на email.
+func newConfirmationCode(reg *pendingRegistration) {
+	reg.Code = utils.GenRandCode()
+	reg.CodeExpiresAt = time.Now().Add(ttl)
+}
+
+// sendRegistrationCode отправляет (асинхронно) код подтверждения регистрации на email пользователя.
+func (s *AuthService) sendRegistrationCode(reg *pendingRegistration) {
+	if err := s.sendConfirmationEmail(reg.User.Email, reg.User.Locale, reg.Code, ttl); err != nil {
+		s.log.Error("Ошибка отправки кода подтверждения на email:", err)
+	}
+}
+
+// checkRegistrationCode сверяет код с текущим кодом регистрации. Попытки учитываются атомарно
+// до сравнения кода: после maxCodeAttempts ошибок регистрация удаляется, и её нужно начать заново.
+func (s *AuthService) checkRegistrationCode(reg *pendingRegistration, code string) error {
+	if time.Now().After(reg.CodeExpiresAt) {
+		return errCodeExpired
+	}
+
+	last, err := s.takeCodeAttempt(registrationKey(reg.User.Email))
+	if errors.Is(err, errCodeNotFound) {
+		return errRegNotFound
+	}
+	if errors.Is(err, errTooManyAttempts) {
+		return errTooManyRegCodes
+	}
+	if err != nil {
+		return err
+	}
+
+	// Сравнение за постоянное время, чтобы код нельзя было подобрать по времени ответа
+	if subtle.ConstantTimeCompare([]byte(reg.Code), []byte(code)) == 1 {
+		return nil
+	}
+
+	if last {
+		s.deleteRegistration(reg)
+		return errTooManyRegCodes
+	}
+
+	return errWrongCode
+}
+
+// getRegistration возвращает незавершённую регистрацию по email.
+func (s *AuthService) getRegistration(email string) (*pendingRegistration, error) {
+	result, err := s.client.Get(ctx, registrationKey(email)).Result()
+	if err == redis.Nil {
+		return nil, errRegNotFound
+	}
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	var reg pendingRegistration
+	if err = json.Unmarshal([]byte(result), &reg); err != nil {
+		s.log.Error("Ошибка при декодировании данных из Redis:", err)
+		return nil, errors.New("не удалось обработать данные пользователя")
+	}
+
+	return &reg, nil
+}
+
+// getOwnRegistration возвращает незавершённую регистрацию по email, только если передан токен,
+// выданный при её создании. Для чужой или заменённой регистрации возвращается errRegNotFound.
+func (s *AuthService) getOwnRegistration(email, registrationToken string) (*pendingRegistration, error) {
+	reg, err := s.getRegistration(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if registrationToken == "" ||
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(registrationToken)), []byte(reg.TokenHash)) != 1 {
+		return nil, errRegNotFound
+	}
+
+	return reg, nil
+}
+
+// saveRegistration сохраняет существующую регистрацию с новым кодом в Redis и сбрасывает счётчик
+// попыток ввода кода. Регистрация, завершённая параллельным запросом, не создаётся заново.
+func (s *AuthService) saveRegistration(reg *pendingRegistration, ttl time.Duration) error {
+	// Сериализуем данные регистрации в JSON, чтобы сохранить их в Redis.
+	regJSON, err := json.Marshal(reg)
+	if err != nil {
+		s.log.Error("не удалось сериализовать данные пользователя:", err)
+		return errInternal
+	}
+
+	key := registrationKey(reg.User.Email)
+	var updated *redis.BoolCmd
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		updated = pipe.SetXX(ctx, key, regJSON, ttl)
+		pipe.Del(ctx, codeAttemptsKey(key))
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !updated.Val() {
+		return errRegNotFound
+	}
+
+	return nil
+}
+
+// updateRegistration обновляет существующую регистрацию, не меняя её время жизни.
+// Если регистрация уже удалена (завершена или истекла), она не создаётся заново и возвращается errRegNotFound.
+func (s *AuthService) updateRegistration(reg *pendingRegistration) error {
+	regJSON, err := json.Marshal(reg)
+	if err != nil {
+		s.log.Error("не удалось сериализовать данные пользователя:", err)
+		return errInternal
+	}
+
+	updated, err := s.client.SetXX(ctx, registrationKey(reg.User.Email), regJSON, redis.KeepTTL).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+	if !updated {
+		return errRegNotFound
+	}
+
+	return nil
+}
+
+// deleteRegistration удаляет незавершённую регистрацию вместе со счётчиком попыток ввода кода.
+func (s *AuthService) deleteRegistration(reg *pendingRegistration) {
+	s.dropCode(registrationKey(reg.User.Email))
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"strings"
+	"testing"
+)
+
+// fakeSMS запоминает отправленные SMS вместо отправки.
+type fakeSMS struct {
+	sent map[string][]string // Номер телефона -> тексты сообщений
+}
+
+func (f *fakeSMS) Send(phone, message string) error {
+	if f.sent == nil {
+		f.sent = make(map[string][]string)
+	}
+	f.sent[phone] = append(f.sent[phone], message)
+
+	return nil
+}
+
+// fakeRegistrationRepo дополняет fakeAccountRepo методами, которые использует регистрация.
+type fakeRegistrationRepo struct {
+	*fakeAccountRepo
+
+	saved []entities.User // Пользователи, сохранённые после подтверждения email
+}
+
+func (r *fakeRegistrationRepo) TestData(user *entities.User) error {
+	return nil
+}
+
+func (r *fakeRegistrationRepo) SaveUser(user *entities.User) error {
+	r.saved = append(r.saved, *user)
+	return nil
+}
+
+// newTestRegistrationService создаёт сервис для тестов регистрации и удаляет её ключи Redis после теста.
+func newTestRegistrationService(t *testing.T, email string) (*AuthService, *fakeRegistrationRepo, *fakeSMS) {
+	t.Helper()
+
+	client := testRedis(t)
+
+	t.Setenv("TOKEN_HASH_KEY", strings.Repeat("k", 32))
+	if err := utils.LoadTokenHashKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeRegistrationRepo{fakeAccountRepo: newFakeAccountRepo()}
+	sms := &fakeSMS{}
+	s := newTestOIDCService(t, repo, client, nil)
+	s.sms = sms
+
+	cleanup := func() {
+		client.Del(ctx, registrationKey(email), codeAttemptsKey(registrationKey(email)), resendThrottleKey(email),
+			phoneVerificationKey(email), codeAttemptsKey(phoneVerificationKey(email)))
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	return s, repo, sms
+}
+
+func newTestRegistrant(password, phone string) *entities.User {
+	return &entities.User{Email: "owner@example.com", Firstname: "Иван", Phone: phone, Password: password}
+}
+
+func TestRegistrationBoundToRegistrant(t *testing.T) {
+	s, repo, _ := newTestRegistrationService(t, "owner@example.com")
+
+	// Посторонний начинает регистрацию с чужим email, своим паролем и телефоном
+	attackerToken, err := s.Register(newTestRegistrant("attacker horse battery staple", "+79160000001"))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// Владелец email регистрируется после него: незавершённая регистрация заменяется
+	s.client.Del(ctx, resendThrottleKey("owner@example.com"))
+	ownerToken, err := s.Register(newTestRegistrant("correct horse battery staple", "+79160000002"))
+	if err != nil {
+		t.Fatalf("повторная регистрация: %v", err)
+	}
+
+	reg, err := s.getRegistration("owner@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Код из письма без токена владельца не завершает регистрацию
+	for _, token := range []string{"", attackerToken} {
+		if err = s.ConfirmEmail("owner@example.com", token, reg.Code); !errors.Is(err, errRegNotFound) {
+			t.Errorf("токен %q: ошибка %v, ожидалась errRegNotFound", token, err)
+		}
+	}
+
+	if err = s.ConfirmEmail("owner@example.com", ownerToken, reg.Code); err != nil {
+		t.Fatalf("ConfirmEmail: %v", err)
+	}
+	if len(repo.saved) != 1 || repo.saved[0].Phone != "+79160000002" ||
+		s.hasher.Compare(repo.saved[0].Password, "correct horse battery staple") != nil {
+		t.Errorf("сохранён не тот пользователь: %+v", repo.saved)
+	}
+}
+
+func TestRegisterThrottled(t *testing.T) {
+	s, _, _ := newTestRegistrationService(t, "owner@example.com")
+
+	if _, err := s.Register(newTestRegistrant("correct horse battery staple", "+79160000002")); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// Повторная регистрация сразу после первой не отправляет ещё одно письмо
+	if _, err := s.Register(newTestRegistrant("correct horse battery staple", "+79160000002")); !errors.Is(err, errResendThrottled) {
+		t.Errorf("ошибка %v, ожидалась errResendThrottled", err)
+	}
+}
+
+func TestResendPhoneCode(t *testing.T) {
+	s, _, sms := newTestRegistrationService(t, "owner@example.com")
+	t.Cleanup(func() { s.client.Del(ctx, phoneResendThrottleKey("owner@example.com")) })
+
+	const phone = "+79160000002"
+	token, err := s.Register(newTestRegistrant("correct horse battery staple", phone))
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// Без токена регистрации код не отправляется
+	if err = s.ResendPhoneCode("owner@example.com", "other-token"); !errors.Is(err, errRegNotFound) {
+		t.Errorf("чужой токен: ошибка %v, ожидалась errRegNotFound", err)
+	}
+
+	if err = s.ResendPhoneCode("owner@example.com", token); err != nil {
+		t.Fatalf("ResendPhoneCode: %v", err)
+	}
+	if len(sms.sent[phone]) != 2 {
+		t.Fatalf("отправлено %d SMS, ожидалось 2", len(sms.sent[phone]))
+	}
+
+	// Повторная отправка сразу после предыдущей ограничена
+	if err = s.ResendPhoneCode("owner@example.com", token); !errors.Is(err, errResendThrottled) {
+		t.Errorf("ошибка %v, ожидалась errResendThrottled", err)
+	}
+
+	// Новый код подтверждает номер
+	if err = s.ConfirmPhone("owner@example.com", storedCode(t, s, phoneVerificationKey("owner@example.com"))); err != nil {
+		t.Fatalf("ConfirmPhone: %v", err)
+	}
+	reg, err := s.getRegistration("owner@example.com")
+	if err != nil || !reg.PhoneVerified {
+		t.Errorf("номер не отмечен подтверждённым: %+v, ошибка %v", reg, err)
+	}
+
+	// Подтверждённому номеру код больше не отправляется
+	s.client.Del(ctx, phoneResendThrottleKey("owner@example.com"))
+	if err = s.ResendPhoneCode("owner@example.com", token); !errors.Is(err, errPhoneVerified) {
+		t.Errorf("ошибка %v, ожидалась errPhoneVerified", err)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/internal/auth/repository"
+	"food-delivery/pkg/middlewares"
+	"food-delivery/pkg/presence"
+	"food-delivery/pkg/utils"
+	"time"
+)
+
+var (
+	errSessionExpired = errors.New("время действия токена просрочено")
+	errSessionInvalid = errors.New("токен не принадлежит активной сессии")
+	errTokenReused    = errors.New("refresh токен уже был использован, сессия завершена")
+)
+
+// GetSessions возвращает все активные сессии пользователя, отмечая сессию, из которой сделан запрос.
+func (s *AuthService) GetSessions(claim *entities.AccessClaim) ([]entities.Session, error) {
+	sessions, err := s.repo.GetSessionsByUserID(claim.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claim.SessionID
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession завершает одну из сессий пользователя (например, на потерянном устройстве).
+func (s *AuthService) DeleteSession(claim *entities.AccessClaim, sessionID string) error {
+	// Удаляем только сессию текущего пользователя, чужие сессии не будут найдены
+	if err := s.repo.DeleteSession(claim.ID, sessionID); err != nil {
+		return err
+	}
+
+	// Access токены завершённой сессии тоже перестают действовать
+	return s.revokeSessionAccess(sessionID)
+}
+
+// SignOutAll завершает все сессии пользователя на всех устройствах.
+func (s *AuthService) SignOutAll(claim *entities.AccessClaim) error {
+	return s.TerminateUserSessions(claim.ID)
+}
+
+// TerminateUserSessions завершает все сессии пользователя и отзывает его access токены.
+// Используется при выходе на всех устройствах и администратором (например, при утере устройства).
+func (s *AuthService) TerminateUserSessions(userID int) error {
+	if err := s.repo.DeleteTokenByID(userID); err != nil {
+		return err
+	}
+
+	// Активных сессий не осталось: пользователь больше не в сети
+	if err := presence.SetOffline(ctx, s.client, userID); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+	}
+
+	return s.RevokeUserAccess(userID)
+}
+
+// setOfflineIfSignedOut снимает отметку присутствия, если у пользователя не осталось активных сессий.
+// Иначе после выхода на последнем устройстве пользователь ещё OnlineWindow считался бы в сети.
+func (s *AuthService) setOfflineIfSignedOut(userID int) {
+	sessions, err := s.repo.GetSessionsByUserID(userID)
+	if err != nil || len(sessions) > 0 {
+		return
+	}
+
+	if err = presence.SetOffline(ctx, s.client, userID); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+	}
+}
+
+// RevokeUserAccess отзывает все выданные пользователю access токены.
+// Используется при выходе на всех устройствах, блокировке пользователя и смене пароля.
+func (s *AuthService) RevokeUserAccess(userID int) error {
+	if err := middlewares.RevokeUserAccessTokens(ctx, s.client, userID, ttlAccess); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// revokeSessionAccess отзывает все access токены, выданные в рамках сессии.
+func (s *AuthService) revokeSessionAccess(sessionID string) error {
+	if err := middlewares.RevokeSessionAccessTokens(ctx, s.client, sessionID, ttlAccess); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return errInternal
+	}
+
+	return nil
+}
+
+// verifySessionToken проверяет подпись refresh токена и то, что он является текущим токеном активной сессии.
+func (s *AuthService) verifySessionToken(refreshToken string) (*entities.RefreshClaim, error) {
+	// Проверка подписи (ключ выбирается по kid) и разбор refresh токена
+	tokenClaim, err := middlewares.ParseSessionRefreshToken(refreshToken)
+	if err != nil {
+		s.log.Error("ошибка при валидации refresh токена:", err)
+		return nil, err
+	}
+
+	// Ищем сессию по хешу токена: в базе данных хранятся только хеши
+	session, err := s.repo.GetSessionByTokenHash(utils.HashToken(refreshToken))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		// Если сессия из токена ещё существует, значит её токен уже был заменён при ротации,
+		// а подписанный нами старый токен предъявлен повторно. Токен мог быть похищен:
+		// завершаем всё семейство токенов (сессию).
+		if _, err = s.repo.GetSession(tokenClaim.SessionID); err == nil {
+			s.revokeTokenFamily(tokenClaim)
+			return nil, errTokenReused
+		}
+		return nil, repository.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Токен должен принадлежать той же сессии и тому же пользователю
+	if session.ID != tokenClaim.SessionID || session.UserID != tokenClaim.ID {
+		return nil, errSessionInvalid
+	}
+
+	// Проверка на то, что сессия ещё не протухла
+	if time.Now().After(session.ExpiresAt) {
+		return nil, errSessionExpired
+	}
+
+	return tokenClaim, nil
+}
+
+// revokeTokenFamily завершает сессию, к которой относится повторно использованный refresh токен,
+// и записывает событие безопасности в лог.
+func (s *AuthService) revokeTokenFamily(tokenClaim *entities.RefreshClaim) {
+	s.log.Info(fmt.Sprintf("СОБЫТИЕ БЕЗОПАСНОСТИ: повторное использование refresh токена (user_id=%d, session_id=%s, jti=%s), сессия завершена",
+		tokenClaim.ID, tokenClaim.SessionID, tokenClaim.RegisteredClaims.ID))
+
+	if err := s.repo.DeleteSession(tokenClaim.ID, tokenClaim.SessionID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		s.log.Error("не удалось завершить сессию при повторном использовании токена:", err)
+	}
+
+	if err := s.revokeSessionAccess(tokenClaim.SessionID); err != nil {
+		s.log.Error("не удалось отозвать access токены сессии:", err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"github.com/redis/go-redis/v9"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ttlSignInAlert           = (time.Hour * 24) * 7 // Время действия ссылки «это был не я»
+	errSignInAlertNotFound   = errors.New("ссылка недействительна или уже использована")
+	errPasswordResetRequired = errors.New("вход по паролю заблокирован, восстановите доступ через сброс пароля")
+)
+
+// signInAlert — вход, о котором пользователь был предупреждён письмом.
+type signInAlert struct {
+	UserID    int    `json:"userId"`
+	SessionID string `json:"sessionId"`
+}
+
+func signInAlertKey(token string) string {
+	return "signin_alert:" + utils.HashToken(token)
+}
+
+// notifyIfNewSignIn отправляет пользователю письмо, если успешный вход выполнен с IP-адреса или устройства,
+// которых нет в его истории входов. Первый вход пользователя не считается подозрительным.
+// Ошибки только логируются: предупреждение не должно мешать входу.
+func (s *AuthService) notifyIfNewSignIn(user *entities.User, entry *entities.LoginHistory) {
+	known, err := s.repo.GetKnownLoginContext(user.ID, entry.IPAddress, entry.Device, entry.OS)
+	if err != nil {
+		s.log.Error("не удалось проверить историю входов:", err)
+		return
+	}
+	if !known.HasHistory || (known.KnownIP && known.KnownDevice) {
+		return
+	}
+
+	token, err := utils.GenRandToken(32)
+	if err != nil {
+		s.log.Error("ошибка при генерации токена предупреждения о входе:", err)
+		return
+	}
+
+	alertJSON, err := json.Marshal(signInAlert{UserID: user.ID, SessionID: entry.SessionID})
+	if err != nil {
+		s.log.Error("не удалось сериализовать предупреждение о входе:", err)
+		return
+	}
+
+	// В Redis хранится только хеш токена из ссылки
+	if err = s.client.Set(ctx, signInAlertKey(token), alertJSON, ttlSignInAlert).Err(); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return
+	}
+
+	link := strings.TrimSuffix(os.Getenv("APP_URL"), "/") + "/api/auth/not-me?token=" + url.QueryEscape(token)
+	device := strings.TrimSpace(entry.Device + " " + entry.OS)
+	if err = s.sendNewSignInEmail(user.Email, user.Locale, entry.IPAddress, device, link); err != nil {
+		s.log.Error("Ошибка отправки предупреждения о входе:", err)
+	}
+}
+
+// NotMe обрабатывает ссылку «это был не я» из письма о новом входе: завершает все сессии пользователя,
+// запрещает вход по паролю до его сброса и отправляет код для сброса пароля.
+func (s *AuthService) NotMe(token string) error {
+	if token == "" {
+		return errInvalidData
+	}
+
+	// Ссылка одноразовая
+	alertJSON, err := s.client.GetDel(ctx, signInAlertKey(token)).Result()
+	if err == redis.Nil {
+		return errSignInAlertNotFound
+	}
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return errInternal
+	}
+
+	var alert signInAlert
+	if err = json.Unmarshal([]byte(alertJSON), &alert); err != nil {
+		s.log.Error("Ошибка при декодировании данных из Redis:", err)
+		return errInternal
+	}
+
+	s.log.Info(fmt.Sprintf("СОБЫТИЕ БЕЗОПАСНОСТИ: пользователь не подтвердил вход (user_id=%d, session_id=%s), все сессии завершены",
+		alert.UserID, alert.SessionID))
+
+	user, err := s.repo.GetUserByID(alert.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.UpdateRecord("users", map[string]interface{}{
+		"password_reset_required": true,
+	}, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.TerminateUserSessions(user.ID); err != nil {
+		return err
+	}
+
+	// Сразу отправляем код для сброса пароля, чтобы пользователь мог восстановить доступ
+	if err = s.ForgotPassword(user.Email); err != nil {
+		s.log.Error("не удалось отправить код сброса пароля:", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/utils"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	signInFreeAttempts = 3  // Количество неудачных попыток без задержки
+	signInLoginLockAt  = 10 // Количество неудачных попыток для логина (email или телефона), после которого вход блокируется
+	signInIPLockAt     = 50 // Количество неудачных попыток с одного IP, после которого вход блокируется
+	signInMFALockAt    = 5  // Количество неверных кодов второго фактора для пользователя, после которого вход блокируется
+)
+
+var (
+	signInFailWindow = time.Minute * 15 // Окно, в течение которого считаются неудачные попытки
+	signInLockTTL    = time.Minute * 15 // Время временной блокировки входа
+	signInMaxDelay   = time.Minute      // Максимальная задержка между попытками
+)
+
+// Ключи Redis для защиты от подбора пароля. scope — "login" (email или телефон), "ip"
+// или "mfa" (ID пользователя, для которого подбирается второй фактор).
+
+func signInFailKey(scope, value string) string {
+	return fmt.Sprintf("signin_fail:%s:%s", scope, value)
+}
+
+func signInLockKey(scope, value string) string {
+	return fmt.Sprintf("signin_lock:%s:%s", scope, value)
+}
+
+func signInDelayKey(scope, value string) string {
+	return fmt.Sprintf("signin_delay:%s:%s", scope, value)
+}
+
+// signInTooManyAttempts формирует ошибку с временем, через которое можно повторить вход.
+func signInTooManyAttempts(wait time.Duration) error {
+	return fmt.Errorf("слишком много неудачных попыток входа, повторите через %d сек", int(math.Ceil(wait.Seconds())))
+}
+
+// checkSignInAllowed проверяет, не заблокирован ли вход для логина (email или телефона) и IP
+// и прошла ли задержка после прошлой ошибки.
+func (s *AuthService) checkSignInAllowed(login, ip string) error {
+	return s.checkNotLocked(
+		signInLockKey("login", login), signInLockKey("ip", ip),
+		signInDelayKey("login", login), signInDelayKey("ip", ip),
+	)
+}
+
+// checkMFAAllowed проверяет, не заблокирован ли ввод второго фактора для пользователя.
+func (s *AuthService) checkMFAAllowed(userID int) error {
+	id := strconv.Itoa(userID)
+	return s.checkNotLocked(signInLockKey("mfa", id), signInDelayKey("mfa", id))
+}
+
+// checkNotLocked возвращает ошибку с временем ожидания, если существует любой из ключей блокировки или задержки.
+func (s *AuthService) checkNotLocked(keys ...string) error {
+	for _, key := range keys {
+		wait, err := s.client.PTTL(ctx, key).Result()
+		if err != nil {
+			s.log.Error("Ошибка при получении данных из Redis:", err)
+			return errInternal
+		}
+		// PTTL возвращает отрицательное значение, если ключа нет
+		if wait > 0 {
+			return signInTooManyAttempts(wait)
+		}
+	}
+
+	return nil
+}
+
+// registerFailedSignIn учитывает неудачную попытку входа: увеличивает счётчики для логина и IP,
+// назначает прогрессивную задержку и при превышении порога временно блокирует вход.
+// Попытка также записывается в login_history.
+func (s *AuthService) registerFailedSignIn(login string, entry *entities.LoginHistory) {
+	s.saveLoginAttempt(entry)
+
+	s.countFailure("login", login, signInLoginLockAt)
+	s.countFailure("ip", entry.IPAddress, signInIPLockAt)
+}
+
+// registerFailedMFA учитывает неверный код второго фактора так же, как неверный пароль (для логина и IP),
+// и дополнительно в счётчике пользователя: пароль уже известен, поэтому подбор второго фактора
+// ограничивается независимо от того, с какого логина и IP он выполняется.
+func (s *AuthService) registerFailedMFA(login string, entry *entities.LoginHistory) {
+	s.registerFailedSignIn(login, entry)
+
+	s.countFailure("mfa", strconv.Itoa(entry.UserID), signInMFALockAt)
+}
+
+// countFailure увеличивает счётчик неудачных попыток для логина или IP.
+func (s *AuthService) countFailure(scope, value string, lockAt int64) {
+	if value == "" {
+		return
+	}
+
+	failKey := signInFailKey(scope, value)
+
+	failures, err := s.client.Incr(ctx, failKey).Result()
+	if err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+		return
+	}
+	// Окно отсчитывается от первой неудачной попытки
+	if failures == 1 {
+		s.client.Expire(ctx, failKey, signInFailWindow)
+	}
+
+	if failures >= lockAt {
+		s.log.Info(fmt.Sprintf("СОБЫТИЕ БЕЗОПАСНОСТИ: вход временно заблокирован (%s=%s, неудачных попыток: %d)",
+			scope, value, failures))
+		s.client.Set(ctx, signInLockKey(scope, value), 1, signInLockTTL)
+		s.client.Del(ctx, failKey)
+		return
+	}
+
+	// Прогрессивная задержка: 1, 2, 4, 8... секунд после исчерпания бесплатных попыток
+	if failures >= signInFreeAttempts {
+		delay := time.Second << (failures - signInFreeAttempts)
+		if delay > signInMaxDelay {
+			delay = signInMaxDelay
+		}
+		s.client.Set(ctx, signInDelayKey(scope, value), 1, delay)
+	}
+}
+
+// resetSignInFailures сбрасывает счётчик неудачных попыток для логина после успешного входа.
+// Счётчик IP не сбрасывается, чтобы вход в свой аккаунт не помогал перебирать чужие пароли.
+func (s *AuthService) resetSignInFailures(login string) {
+	if err := s.client.Del(ctx, signInFailKey("login", login), signInDelayKey("login", login)).Err(); err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+	}
+}
+
+// resetMFAFailures сбрасывает счётчики неудачных попыток для логина и второго фактора после успешного входа с 2FA.
+func (s *AuthService) resetMFAFailures(login string, userID int) {
+	s.resetSignInFailures(login)
+
+	id := strconv.Itoa(userID)
+	if err := s.client.Del(ctx, signInFailKey("mfa", id), signInDelayKey("mfa", id)).Err(); err != nil {
+		s.log.Error("Ошибка при удалении данных из Redis:", err)
+	}
+}
+
+// saveLoginAttempt записывает попытку входа в login_history. Ошибка записи не прерывает вход.
+func (s *AuthService) saveLoginAttempt(entry *entities.LoginHistory) {
+	entry.Device, entry.OS = utils.ParseUserAgent(entry.UserAgent)
+
+	if err := s.repo.SaveLoginHistory(entry); err != nil {
+		s.log.Error("не удалось сохранить попытку входа:", err)
+	}
+}
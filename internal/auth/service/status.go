@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/presence"
+)
+
+var (
+	errAccountUnavailable = errors.New("аккаунт заблокирован, приостановлен или удалён")
+	errInvalidStatus      = errors.New("неизвестный статус аккаунта")
+)
+
+// ChangeUserStatus меняет статус аккаунта (доступно администраторам) по правилам переходов
+// из entities.CanChangeUserStatus. Если новый статус не позволяет входить в аккаунт,
+// все сессии пользователя завершаются, а его access токены отзываются.
+func (s *AuthService) ChangeUserStatus(userID int, status string) error {
+	if !entities.IsValidUserStatus(status) {
+		return errInvalidStatus
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Status == status {
+		return nil
+	}
+
+	if !entities.CanChangeUserStatus(user.Status, status) {
+		return fmt.Errorf("переход из статуса %s в статус %s запрещён", user.Status, status)
+	}
+
+	// При удалении также запоминается время удаления для последующего обезличивания
+	if status == entities.UserStatusRemoved {
+		err = s.repo.MarkUserRemoved(userID)
+	} else {
+		err = s.repo.ChangeUserStatus(userID, user.Status, status)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !entities.CanSignIn(status) {
+		return s.TerminateUserSessions(userID)
+	}
+
+	return nil
+}
+
+// GetPresence возвращает, в сети ли пользователь, и время его последней активности.
+func (s *AuthService) GetPresence(userID int) (*entities.Presence, error) {
+	online, lastSeen, err := presence.Get(ctx, s.client, userID)
+	if err != nil {
+		s.log.Error("Ошибка при получении данных из Redis:", err)
+		return nil, errInternal
+	}
+
+	result := &entities.Presence{
+		UserID: userID,
+		Online: online,
+	}
+	if !lastSeen.IsZero() {
+		result.LastSeen = &lastSeen
+	}
+
+	return result, nil
+}
+
+// touchPresence отмечает активность пользователя. Ошибка не прерывает запрос.
+func (s *AuthService) touchPresence(userID int) {
+	if err := presence.Touch(ctx, s.client, userID); err != nil {
+		s.log.Error("Ошибка при записи данных в Redis:", err)
+	}
+}
@@ -0,0 +1,12 @@
+-- Возврат к одному refresh-токену на пользователя
+DELETE FROM tokens;
+
+DROP INDEX IF EXISTS idx_tokens_user_id;
+
+ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_session_id_key,
+    DROP COLUMN IF EXISTS session_id,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS last_used_at,
+    ADD CONSTRAINT tokens_user_id_key UNIQUE (user_id);
@@ -0,0 +1,18 @@
+-- Сессии пользователей
+-- Вместо одного refresh-токена на пользователя в tokens хранится по строке на каждое устройство
+
+-- Старые токены не содержат ID сессии, поэтому пользователям придётся войти заново
+DELETE FROM tokens;
+
+-- Снимаем уникальность user_id (она позволяла держать только одну сессию на пользователя)
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_user_id_key;
+
+ALTER TABLE tokens
+    ADD COLUMN session_id VARCHAR(64) NOT NULL,            -- Уникальный идентификатор сессии (устройства)
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',        -- User-Agent устройства
+    ADD COLUMN ip_address INET,                            -- IP-адрес, с которого выполнен вход
+    ADD COLUMN last_used_at TIMESTAMP DEFAULT NOW(),       -- Дата и время последнего использования сессии
+    ADD CONSTRAINT tokens_session_id_key UNIQUE (session_id);
+
+-- Индекс для ускорения поиска сессий пользователя
+CREATE INDEX idx_tokens_user_id ON tokens(user_id);
@@ -0,0 +1,7 @@
+-- Исходные токены из хешей не восстановить, поэтому все сессии завершаются
+DELETE FROM tokens;
+
+ALTER TABLE tokens
+    DROP CONSTRAINT IF EXISTS tokens_token_hash_key,
+    DROP COLUMN IF EXISTS token_hash,
+    ADD COLUMN token TEXT NOT NULL; -- Токен (refresh-token)
@@ -0,0 +1,14 @@
+-- Хранение refresh-токенов в виде ключевого хеша (HMAC-SHA256) вместо исходного JWT
+-- Перед применением миграции необходимо передать ключ из TOKEN_HASH_KEY в настройку сессии:
+--   SET app.token_hash_key = '<значение TOKEN_HASH_KEY>';
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE tokens ADD COLUMN token_hash VARCHAR(64); -- Хеш refresh-токена
+
+-- Переносим существующие токены: хешируем их тем же ключом, что и приложение
+UPDATE tokens SET token_hash = encode(hmac(token, current_setting('app.token_hash_key'), 'sha256'), 'hex');
+
+ALTER TABLE tokens
+    ALTER COLUMN token_hash SET NOT NULL,
+    ADD CONSTRAINT tokens_token_hash_key UNIQUE (token_hash),
+    DROP COLUMN token; -- Исходные токены больше не храним
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_login_history_email;
+DROP INDEX IF EXISTS idx_login_history_ip_address;
+
+-- Неудачные попытки не относятся к входам пользователей
+DELETE FROM login_history WHERE success = FALSE;
+
+ALTER TABLE login_history
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS success,
+    DROP COLUMN IF EXISTS failure_reason;
@@ -0,0 +1,9 @@
+-- Сохранение неудачных попыток входа в login_history
+ALTER TABLE login_history
+    ADD COLUMN email VARCHAR(255),                    -- Email, с которым выполнялась попытка входа
+    ADD COLUMN success BOOLEAN NOT NULL DEFAULT TRUE, -- Признак успешного входа
+    ADD COLUMN failure_reason VARCHAR(50);            -- Причина неудачи (unknown_email, wrong_password, blocked, locked)
+
+-- Индексы для поиска атак по email и IP-адресу
+CREATE INDEX idx_login_history_email ON login_history(email);
+CREATE INDEX idx_login_history_ip_address ON login_history(ip_address);
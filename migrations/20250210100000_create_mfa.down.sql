@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_recovery_codes; -- Удаление таблицы кодов восстановления
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_secret,
+    DROP COLUMN IF EXISTS totp_enabled;
@@ -0,0 +1,17 @@
+-- Двухфакторная аутентификация (TOTP)
+ALTER TABLE users
+    ADD COLUMN totp_secret TEXT,                          -- Секрет TOTP (base32), задаётся при подключении 2FA
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE; -- Признак того, что 2FA подтверждена и включена
+
+-- Таблица кодов восстановления
+-- Одноразовые коды для входа, если приложение-аутентификатор недоступно
+CREATE TABLE mfa_recovery_codes (
+                                    id SERIAL PRIMARY KEY,                                    -- Уникальный идентификатор кода
+                                    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Ссылка на ID пользователя из таблицы users
+                                    code_hash VARCHAR(64) NOT NULL,                           -- Хеш кода восстановления
+                                    used_at TIMESTAMP,                                        -- Дата и время использования (NULL, если код не использован)
+                                    created_at TIMESTAMP DEFAULT NOW()                        -- Дата и время создания записи
+);
+
+-- Индекс для ускорения поиска кодов пользователя
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
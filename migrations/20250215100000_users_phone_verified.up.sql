@@ -0,0 +1,2 @@
+-- Подтверждение номера телефона
+ALTER TABLE users ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT FALSE; -- Признак того, что номер подтверждён кодом из SMS
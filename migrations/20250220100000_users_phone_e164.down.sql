@@ -0,0 +1,6 @@
+-- Возврат исходных (ненормализованных) номеров из phone_raw.
+-- Отметка подтверждения, снятая с освобождённых номеров, не восстанавливается.
+UPDATE users SET phone = phone_raw WHERE phone_raw IS NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_raw;
+-- Возврат NOT NULL возможен, только если у всех пользователей указан телефон
+ALTER TABLE users ALTER COLUMN phone SET NOT NULL;
@@ -0,0 +1,66 @@
+-- Приведение существующих номеров телефонов к формату E.164 по тем же правилам, что и utils.NormalizePhone.
+-- Исходные значения сохраняются в phone_raw: по ним выполняется откат и разбор спорных записей.
+ALTER TABLE users ADD COLUMN phone_raw VARCHAR(50);
+UPDATE users SET phone_raw = phone;
+
+-- Номер, который не удалось нормализовать или который совпал с номером другого пользователя, освобождается
+ALTER TABLE users ALTER COLUMN phone DROP NOT NULL;
+
+-- Нормализованные номера (цифры без «+») считаются заранее, чтобы найти совпадения до изменения users
+CREATE TEMP TABLE phone_e164 AS
+SELECT id,
+       CASE
+           WHEN raw !~ '^\+?[0-9]+$' THEN NULL
+           WHEN raw LIKE '+%' THEN substr(raw, 2)
+           WHEN raw LIKE '00%' THEN substr(raw, 3)
+           WHEN raw ~ '^[78][0-9]{10}$' THEN '7' || substr(raw, 2)
+           WHEN raw ~ '^[0-9]{10}$' THEN '7' || raw
+       END AS digits
+FROM (SELECT id, regexp_replace(phone, '[ ().-]', '', 'g') AS raw FROM users WHERE phone IS NOT NULL) p;
+
+-- E.164 допускает от 8 до 15 цифр, код страны не начинается с нуля
+UPDATE phone_e164 SET digits = NULL WHERE digits !~ '^[1-9][0-9]{7,14}$';
+
+-- Разные записи одного номера: номер остаётся у записи, подтвердившей его, а среди равных — у самой новой.
+-- У остальных номер освобождается и снимается отметка подтверждения.
+CREATE TEMP TABLE phone_duplicates AS
+SELECT id
+FROM (SELECT e.id,
+             row_number() OVER (PARTITION BY e.digits ORDER BY u.phone_verified DESC, u.id DESC) AS rn
+      FROM phone_e164 e
+               JOIN users u ON u.id = e.id
+      WHERE e.digits IS NOT NULL) d
+WHERE rn > 1;
+
+DO $$
+DECLARE
+    invalid_ids   TEXT;
+    duplicate_ids TEXT;
+BEGIN
+    SELECT string_agg(id::TEXT, ', ' ORDER BY id) INTO invalid_ids FROM phone_e164 WHERE digits IS NULL;
+    SELECT string_agg(id::TEXT, ', ' ORDER BY id) INTO duplicate_ids FROM phone_duplicates;
+
+    IF invalid_ids IS NOT NULL THEN
+        RAISE NOTICE 'номера не приведены к E.164 и освобождены (users.id: %), исходные значения в phone_raw', invalid_ids;
+    END IF;
+    IF duplicate_ids IS NOT NULL THEN
+        RAISE NOTICE 'номера совпали с номерами других пользователей и освобождены (users.id: %), исходные значения в phone_raw', duplicate_ids;
+    END IF;
+END $$;
+
+UPDATE users u
+SET phone          = NULL,
+    phone_verified = FALSE
+FROM phone_e164 e
+WHERE e.id = u.id
+  AND (e.digits IS NULL OR e.id IN (SELECT id FROM phone_duplicates));
+
+UPDATE users u
+SET phone = '+' || e.digits
+FROM phone_e164 e
+WHERE e.id = u.id
+  AND e.digits IS NOT NULL
+  AND e.id NOT IN (SELECT id FROM phone_duplicates);
+
+DROP TABLE phone_duplicates;
+DROP TABLE phone_e164;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_identities;
+-- Возврат NOT NULL возможен, только если у всех пользователей указан телефон
+ALTER TABLE users ALTER COLUMN phone SET NOT NULL;
@@ -0,0 +1,15 @@
+-- Вход через внешних OIDC провайдеров (Google, Apple, Yandex)
+-- Пользователи, зарегистрированные через провайдера, могут не иметь номера телефона
+ALTER TABLE users ALTER COLUMN phone DROP NOT NULL;
+
+CREATE TABLE user_identities (
+                                 id SERIAL PRIMARY KEY,                                       -- Уникальный идентификатор привязки
+                                 user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Ссылка на пользователя
+                                 provider VARCHAR(50) NOT NULL,                               -- Имя провайдера (google, apple, yandex)
+                                 subject VARCHAR(255) NOT NULL,                               -- Идентификатор пользователя у провайдера (sub)
+                                 email VARCHAR(255),                                          -- Email из ID токена на момент привязки
+                                 created_at TIMESTAMP DEFAULT NOW(),                          -- Дата и время привязки
+                                 UNIQUE (provider, subject)
+);
+
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_removed_at;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS anonymized_at,
+    DROP COLUMN IF EXISTS removed_at;
@@ -0,0 +1,7 @@
+-- Самостоятельное удаление аккаунта и обезличивание данных
+ALTER TABLE users
+    ADD COLUMN removed_at TIMESTAMP,     -- Дата и время удаления аккаунта пользователем (статус removed)
+    ADD COLUMN anonymized_at TIMESTAMP;  -- Дата и время обезличивания персональных данных
+
+-- Индекс для поиска аккаунтов, ожидающих обезличивания
+CREATE INDEX idx_users_removed_at ON users(removed_at) WHERE anonymized_at IS NULL;
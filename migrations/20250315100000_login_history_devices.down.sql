@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_login_history_user_id_login_time;
+ALTER TABLE login_history
+    DROP COLUMN IF EXISTS session_id,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS device,
+    DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,9 @@
+-- Сведения об устройстве и сессии в истории входов
+ALTER TABLE login_history
+    ADD COLUMN user_agent TEXT,        -- Заголовок User-Agent клиента
+    ADD COLUMN device VARCHAR(20),     -- Тип устройства (desktop, mobile, tablet, bot, unknown)
+    ADD COLUMN os VARCHAR(50),         -- Операционная система, определённая по User-Agent
+    ADD COLUMN session_id VARCHAR(64); -- ID сессии, созданной при успешном входе
+
+-- Индекс для постраничного просмотра истории пользователя
+CREATE INDEX idx_login_history_user_id_login_time ON login_history(user_id, login_time DESC);
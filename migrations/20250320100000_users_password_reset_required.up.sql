@@ -0,0 +1,2 @@
+-- Принудительный сброс пароля после подтверждённого пользователем подозрительного входа
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE; -- Вход по паролю запрещён до сброса пароля
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+UPDATE users SET status = 'suspended' WHERE status = 'pending';
+ALTER TABLE users ALTER COLUMN status SET DEFAULT 'suspended';
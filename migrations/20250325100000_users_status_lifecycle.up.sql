@@ -0,0 +1,7 @@
+-- Статус аккаунта больше не отражает вход и выход пользователя (присутствие хранится в Redis).
+-- Раньше 'suspended' означал «вышел из аккаунта», поэтому такие аккаунты становятся активными.
+UPDATE users SET status = 'active' WHERE status = 'suspended';
+
+ALTER TABLE users ALTER COLUMN status SET DEFAULT 'active';
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('pending', 'active', 'suspended', 'blocked', 'removed')); -- Допустимые статусы аккаунта
@@ -0,0 +1,2 @@
+-- Язык, на котором пользователю отправляются письма
+ALTER TABLE users ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT 'ru'; -- Язык писем (ru, en)
@@ -0,0 +1,3 @@
+-- Откат не выполнится, если один и тот же неподтверждённый номер указан у нескольких пользователей
+DROP INDEX IF EXISTS users_phone_verified_key;
+ALTER TABLE users ADD CONSTRAINT users_phone_key UNIQUE (phone);
@@ -0,0 +1,4 @@
+-- Номер телефона занят только после подтверждения кодом из SMS: неподтверждённый номер,
+-- указанный в чужом аккаунте, не мешает зарегистрироваться его настоящему владельцу
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_key;
+CREATE UNIQUE INDEX users_phone_verified_key ON users (phone) WHERE phone_verified;
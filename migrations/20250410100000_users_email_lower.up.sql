@@ -0,0 +1,2 @@
+-- Поиск пользователя по email выполняется без учёта регистра
+CREATE INDEX idx_users_email_lower ON users (lower(email));
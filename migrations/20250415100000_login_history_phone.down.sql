@@ -0,0 +1,3 @@
+UPDATE login_history SET email = phone WHERE email IS NULL AND phone IS NOT NULL;
+ALTER TABLE login_history
+    DROP COLUMN IF EXISTS phone;
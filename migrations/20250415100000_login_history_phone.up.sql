@@ -0,0 +1,6 @@
+-- Телефон, с которым выполнялась попытка входа по SMS, хранится отдельно от email
+ALTER TABLE login_history
+    ADD COLUMN phone VARCHAR(20); -- Телефон, если попытка входа выполнялась по SMS
+
+-- Переносим телефоны, ранее записанные в колонку email
+UPDATE login_history SET phone = email, email = NULL WHERE email NOT LIKE '%@%';
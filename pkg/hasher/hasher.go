@@ -0,0 +1,169 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatchedPassword = errors.New("пароль не совпадает с хешем")
+	errUnknownHashFormat  = errors.New("неизвестный формат хеша пароля")
+	errUnknownAlgorithm   = errors.New("неизвестный алгоритм хеширования пароля")
+)
+
+// Hasher хеширует пароли и проверяет их по сохранённому хешу.
+// Формат хеша определяет алгоритм и его параметры, поэтому хеши разных версий могут храниться одновременно.
+type Hasher interface {
+	// Hash возвращает хеш пароля, посчитанный текущим алгоритмом с текущими параметрами.
+	Hash(password string) (string, error)
+	// Compare проверяет пароль по хешу любого поддерживаемого алгоритма.
+	// Если пароль не подходит, возвращается ErrMismatchedPassword.
+	Compare(encoded, password string) error
+	// NeedsRehash сообщает, что хеш посчитан устаревшим алгоритмом или с устаревшими параметрами.
+	NeedsRehash(encoded string) bool
+}
+
+// Config — текущий алгоритм хеширования и параметры алгоритмов.
+type Config struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+	// Argon2MaxMemory — сколько памяти (в КиБ) могут одновременно занимать вычисления argon2id;
+	// остальные ждут своей очереди. 0 — DefaultArgon2MaxMemory.
+	Argon2MaxMemory uint32
+}
+
+// PasswordHasher хеширует пароли текущим алгоритмом и проверяет хеши argon2id и bcrypt.
+// Вычисления argon2id ограничены по памяти (см. Config.Argon2MaxMemory).
+type PasswordHasher struct {
+	cfg     Config
+	limiter *memoryLimiter
+}
+
+func NewPasswordHasher(cfg Config) (*PasswordHasher, error) {
+	switch cfg.Algorithm {
+	case AlgorithmArgon2id:
+		if err := cfg.Argon2.validate(); err != nil {
+			return nil, err
+		}
+	case AlgorithmBcrypt:
+		if err := validateBcryptCost(cfg.BcryptCost); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownAlgorithm, cfg.Algorithm)
+	}
+
+	if cfg.Argon2MaxMemory == 0 {
+		cfg.Argon2MaxMemory = DefaultArgon2MaxMemory
+	}
+
+	// Хеши argon2id проверяются и при текущем алгоритме bcrypt, поэтому размер слота нужен всегда
+	slotMemory := cfg.Argon2.Memory
+	if slotMemory == 0 {
+		slotMemory = DefaultArgon2Params.Memory
+	}
+
+	return &PasswordHasher{cfg: cfg, limiter: newMemoryLimiter(cfg.Argon2MaxMemory, slotMemory)}, nil
+}
+
+// LoadFromEnv создаёт PasswordHasher по переменным окружения:
+// PASSWORD_HASH_ALGORITHM (argon2id или bcrypt, по умолчанию argon2id), PASSWORD_BCRYPT_COST,
+// PASSWORD_ARGON2_MEMORY (в КиБ), PASSWORD_ARGON2_ITERATIONS, PASSWORD_ARGON2_PARALLELISM
+// и PASSWORD_ARGON2_MAX_MEMORY (в КиБ, память для одновременных вычислений argon2id).
+// Незаданные параметры берутся из DefaultBcryptCost, DefaultArgon2Params и DefaultArgon2MaxMemory.
+func LoadFromEnv() (*PasswordHasher, error) {
+	cfg := Config{
+		Algorithm:  os.Getenv("PASSWORD_HASH_ALGORITHM"),
+		BcryptCost: DefaultBcryptCost,
+		Argon2:     DefaultArgon2Params,
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmArgon2id
+	}
+
+	var err error
+	if cfg.BcryptCost, err = envInt("PASSWORD_BCRYPT_COST", cfg.BcryptCost); err != nil {
+		return nil, err
+	}
+
+	memory, err := envInt("PASSWORD_ARGON2_MEMORY", int(cfg.Argon2.Memory))
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := envInt("PASSWORD_ARGON2_ITERATIONS", int(cfg.Argon2.Iterations))
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := envInt("PASSWORD_ARGON2_PARALLELISM", int(cfg.Argon2.Parallelism))
+	if err != nil {
+		return nil, err
+	}
+	maxMemory, err := envInt("PASSWORD_ARGON2_MAX_MEMORY", DefaultArgon2MaxMemory)
+	if err != nil {
+		return nil, err
+	}
+	if memory <= 0 || iterations <= 0 || parallelism <= 0 || parallelism > 255 || maxMemory <= 0 {
+		return nil, errors.New("некорректные параметры argon2id")
+	}
+	cfg.Argon2.Memory = uint32(memory)
+	cfg.Argon2.Iterations = uint32(iterations)
+	cfg.Argon2.Parallelism = uint8(parallelism)
+	cfg.Argon2MaxMemory = uint32(maxMemory)
+
+	return NewPasswordHasher(cfg)
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.cfg.Algorithm == AlgorithmBcrypt {
+		return hashBcrypt(password, h.cfg.BcryptCost)
+	}
+
+	return hashArgon2id(password, h.cfg.Argon2, h.limiter)
+}
+
+func (h *PasswordHasher) Compare(encoded, password string) error {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return compareArgon2id(encoded, password, h.limiter)
+	case isBcryptHash(encoded):
+		return compareBcrypt(encoded, password)
+	default:
+		return errUnknownHashFormat
+	}
+}
+
+func (h *PasswordHasher) NeedsRehash(encoded string) bool {
+	switch h.cfg.Algorithm {
+	case AlgorithmArgon2id:
+		params, err := decodeArgon2idParams(encoded)
+		return err != nil || params != h.cfg.Argon2
+	case AlgorithmBcrypt:
+		cost, err := bcryptCost(encoded)
+		return err != nil || cost != h.cfg.BcryptCost
+	default:
+		return false
+	}
+}
+
+func envInt(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("некорректное значение %s: %v", name, err)
+	}
+
+	return n, nil
+}
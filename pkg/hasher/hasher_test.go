@@ -0,0 +1,188 @@
+package hasher
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testArgon2Params — лёгкие параметры argon2id, чтобы тесты не выделяли по 64 МиБ на хеш.
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasher(t *testing.T, cfg Config) *PasswordHasher {
+	t.Helper()
+
+	if cfg.Argon2 == (Argon2Params{}) {
+		cfg.Argon2 = testArgon2Params
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = 4
+	}
+
+	h, err := NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+
+	return h
+}
+
+func TestHashCompareRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := newTestHasher(t, Config{Algorithm: algorithm})
+
+			first, err := h.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			second, err := h.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if first == second {
+				t.Error("хеши одного пароля совпали: соль не используется")
+			}
+
+			if err = h.Compare(first, "correct horse battery staple"); err != nil {
+				t.Errorf("верный пароль не прошёл проверку: %v", err)
+			}
+			if err = h.Compare(first, "correct horse battery stapler"); !errors.Is(err, ErrMismatchedPassword) {
+				t.Errorf("неверный пароль: ошибка %v, ожидалась ErrMismatchedPassword", err)
+			}
+			if h.NeedsRehash(first) {
+				t.Error("свежий хеш помечен как устаревший")
+			}
+		})
+	}
+}
+
+func TestCompareOtherAlgorithm(t *testing.T) {
+	argonHasher := newTestHasher(t, Config{Algorithm: AlgorithmArgon2id})
+	bcryptHasher := newTestHasher(t, Config{Algorithm: AlgorithmBcrypt})
+
+	argonHash, err := argonHasher.Hash("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcryptHasher.Hash("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Хеши, посчитанные прежним алгоритмом, продолжают проверяться после смены алгоритма
+	if err = bcryptHasher.Compare(argonHash, "secret-password"); err != nil {
+		t.Errorf("хеш argon2id не проверяется при алгоритме bcrypt: %v", err)
+	}
+	if err = argonHasher.Compare(bcryptHash, "secret-password"); err != nil {
+		t.Errorf("хеш bcrypt не проверяется при алгоритме argon2id: %v", err)
+	}
+}
+
+func TestCompareInvalidHash(t *testing.T) {
+	h := newTestHasher(t, Config{Algorithm: AlgorithmArgon2id})
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    error
+	}{
+		{"пустой хеш", "", errUnknownHashFormat},
+		{"неизвестный формат", "$5$rounds=5000$salt$hash", errUnknownHashFormat},
+		{"argon2id без ключа", "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA", errInvalidArgon2Hash},
+		{"argon2id другой версии", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", errInvalidArgon2Hash},
+		{"argon2id без итераций", "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", errInvalidArgon2Hash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := h.Compare(tt.encoded, "password"); !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current := newTestHasher(t, Config{Algorithm: AlgorithmArgon2id})
+
+	heavier := testArgon2Params
+	heavier.Iterations = 2
+	oldArgon := newTestHasher(t, Config{Algorithm: AlgorithmArgon2id, Argon2: heavier})
+	oldBcrypt := newTestHasher(t, Config{Algorithm: AlgorithmBcrypt, BcryptCost: 5})
+
+	hash := func(h *PasswordHasher) string {
+		encoded, err := h.Hash("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+
+	tests := []struct {
+		name    string
+		hasher  *PasswordHasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id с текущими параметрами", current, hash(current), false},
+		{"argon2id с другими параметрами", current, hash(oldArgon), true},
+		{"bcrypt при текущем argon2id", current, hash(oldBcrypt), true},
+		{"argon2id при текущем bcrypt", newTestHasher(t, Config{Algorithm: AlgorithmBcrypt, BcryptCost: 5}), hash(current), true},
+		{"bcrypt с текущей стоимостью", oldBcrypt, hash(oldBcrypt), false},
+		{"bcrypt с другой стоимостью", newTestHasher(t, Config{Algorithm: AlgorithmBcrypt, BcryptCost: 6}), hash(oldBcrypt), true},
+		{"нераспознанный хеш", current, "garbage", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	// Бюджет на два вычисления с памятью 64 КиБ
+	limiter := newMemoryLimiter(128, 64)
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release := limiter.acquire(64)
+			defer release()
+
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("одновременно выполнялось %d вычислений, бюджет позволяет 2", peak.Load())
+	}
+
+	// Вычисление с памятью больше бюджета занимает весь бюджет, но не блокируется навсегда
+	release := limiter.acquire(1024)
+	if len(limiter.slots) != cap(limiter.slots) {
+		t.Errorf("тяжёлое вычисление заняло %d слотов из %d", len(limiter.slots), cap(limiter.slots))
+	}
+	release()
+	if len(limiter.slots) != 0 {
+		t.Errorf("после освобождения занято %d слотов", len(limiter.slots))
+	}
+}
@@ -0,0 +1,52 @@
+package hasher
+
+import "sync"
+
+// DefaultArgon2MaxMemory — память (в КиБ), которую по умолчанию могут одновременно занимать вычисления
+// argon2id: 256 МиБ, то есть четыре хеша с параметрами по умолчанию.
+const DefaultArgon2MaxMemory = 256 * 1024
+
+// memoryLimiter ограничивает суммарную память, одновременно занятую вычислениями argon2id.
+// Без ограничения каждый вход выделяет Memory КиБ, и поток запросов на вход исчерпывает память процесса.
+// Бюджет делится на слоты размером с Memory текущих параметров; хеш со старыми, более тяжёлыми
+// параметрами занимает несколько слотов.
+type memoryLimiter struct {
+	// Слоты одного вычисления набираются под мьютексом: два вычисления, набравшие по половине
+	// бюджета, иначе ждали бы друг друга бесконечно
+	mu         sync.Mutex
+	slots      chan struct{}
+	slotMemory uint32
+}
+
+func newMemoryLimiter(budget, slotMemory uint32) *memoryLimiter {
+	count := budget / slotMemory
+	if count < 1 {
+		count = 1
+	}
+
+	return &memoryLimiter{slots: make(chan struct{}, count), slotMemory: slotMemory}
+}
+
+// acquire ждёт, пока освободится память для вычисления с параметром memory (в КиБ), и возвращает
+// функцию, освобождающую её.
+func (l *memoryLimiter) acquire(memory uint32) func() {
+	count := int((uint64(memory) + uint64(l.slotMemory) - 1) / uint64(l.slotMemory))
+	if count < 1 {
+		count = 1
+	}
+	if count > cap(l.slots) {
+		count = cap(l.slots)
+	}
+
+	l.mu.Lock()
+	for i := 0; i < count; i++ {
+		l.slots <- struct{}{}
+	}
+	l.mu.Unlock()
+
+	return func() {
+		for i := 0; i < count; i++ {
+			<-l.slots
+		}
+	}
+}
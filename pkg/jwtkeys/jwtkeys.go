@@ -0,0 +1,190 @@
+// Package jwtkeys хранит ключи подписи JWT (RS256 и EdDSA) и публикует их открытые части в формате JWKS.
+// Ключей может быть несколько: токены подписываются активным ключом, а проверяются любым из загруженных,
+// поэтому ключ можно сменить, не обрывая сессии с ещё не истёкшими токенами.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoSigningKey = errors.New("ключ подписи JWT не настроен")
+	ErrUnknownKey   = errors.New("неизвестный ключ подписи JWT")
+)
+
+// Key — ключ подписи. У ключа, оставленного только для проверки старых токенов, Private равен nil.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeyRing — набор ключей подписи с одним активным ключом.
+type KeyRing struct {
+	keys      map[string]*Key
+	activeKID string
+}
+
+var (
+	defaultMu   sync.RWMutex
+	defaultRing *KeyRing
+)
+
+// SetDefault задаёт набор ключей, используемый генераторами и парсерами токенов приложения.
+func SetDefault(ring *KeyRing) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultRing = ring
+}
+
+// Default возвращает набор ключей, заданный через SetDefault (nil, если ключи не загружены).
+func Default() *KeyRing {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultRing
+}
+
+// LoadFromEnv загружает ключи из каталога JWT_KEYS_DIR. Каждый файл <kid>.pem содержит закрытый
+// (PKCS#8, PKCS#1) или открытый (PKIX) ключ RSA или Ed25519; имя файла становится kid.
+// Токены подписываются ключом JWT_ACTIVE_KID.
+func LoadFromEnv() (*KeyRing, error) {
+	return Load(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"))
+}
+
+// Load загружает ключи из каталога dir и делает активным ключ activeKID.
+func Load(dir, activeKID string) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*Key), activeKID: activeKID}
+
+	if dir == "" {
+		return nil, fmt.Errorf("%w: не задан JWT_KEYS_DIR", ErrNoSigningKey)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := ParseKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("ключ %s: %w", file, err)
+		}
+		ring.keys[kid] = key
+	}
+
+	active, ok := ring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("%w: ключ JWT_ACTIVE_KID=%q не найден в %s", ErrNoSigningKey, activeKID, dir)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("%w: для ключа %q нет закрытой части", ErrNoSigningKey, activeKID)
+	}
+
+	return ring, nil
+}
+
+// ParseKey разбирает PEM-блок с закрытым или открытым ключом RSA либо Ed25519.
+func ParseKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("файл не содержит PEM-блока")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип PEM-блока %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("неподдерживаемый тип ключа %T", parsed)
+	}
+
+	if rsaKey, ok := key.Public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("размер ключа RSA должен быть не меньше 2048 бит")
+	}
+
+	return key, nil
+}
+
+// Sign подписывает claims активным ключом и добавляет в заголовок токена его kid.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	if r == nil {
+		return "", ErrNoSigningKey
+	}
+
+	key := r.keys[r.activeKID]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc возвращает ключ проверки подписи по kid из заголовка токена. Алгоритм токена должен
+// совпадать с алгоритмом ключа, чтобы открытый ключ нельзя было использовать как секрет HMAC.
+// Токены без kid (подписанные симметричным ключом до перехода на асимметричные) не принимаются.
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if r == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnknownKey
+	}
+
+	return key.Public, nil
+}
+
+// kids возвращает отсортированный список kid, чтобы JWKS отдавался в стабильном порядке.
+func (r *KeyRing) kids() []string {
+	kids := make([]string, 0, len(r.keys))
+	for kid := range r.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	return kids
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import "time"
+
+// Данные для шаблонов писем.
+
+// CodeData — письмо с одноразовым кодом (подтверждение email, сброс пароля, удаление аккаунта).
+type CodeData struct {
+	Code       string
+	TTLMinutes int // Сколько минут действует код
+}
+
+// EmailChangedData — уведомление прежнего адреса о начатой или завершённой смене email.
+type EmailChangedData struct {
+	NewEmail string
+}
+
+// NewSignInData — предупреждение о входе с нового IP-адреса или устройства.
+type NewSignInData struct {
+	IPAddress string
+	Device    string
+	Time      time.Time
+	Link      string // Ссылка «это был не я»
+}
+
+// OrderReceiptData — чек заказа. Суммы указываются в копейках.
+type OrderReceiptData struct {
+	OrderID   int
+	CreatedAt time.Time
+	Items     []OrderReceiptItem
+	Delivery  int64 // Стоимость доставки
+	Total     int64
+	Currency  string // Например, RUB
+}
+
+// OrderReceiptItem — позиция в чеке заказа.
+type OrderReceiptItem struct {
+	Name     string
+	Quantity int
+	Price    int64 // Цена за единицу
+	Amount   int64 // Цена × количество
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"fmt"
+	"food-delivery/pkg/logger"
+	"net/smtp"
+	"os"
+)
+
+// SMTPConfig — параметры SMTP-сервера для отправки писем.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	From     string // Адрес отправителя
+	Password string
+}
+
+// Mailer отправляет транзакционные письма (подтверждение, сброс пароля, уведомления безопасности, чеки заказов),
+// собранные из шаблонов на языке получателя.
+type Mailer struct {
+	smtp     SMTPConfig
+	renderer *Renderer
+	log      *logger.Logger
+}
+
+func NewMailer(cfg SMTPConfig, renderer *Renderer, log *logger.Logger) *Mailer {
+	return &Mailer{
+		smtp:     cfg,
+		renderer: renderer,
+		log:      log,
+	}
+}
+
+// LoadFromEnv создаёт Mailer по переменным окружения: MAIL_HOST, MAIL_PORT, MAIL_FROM, MAIL_PASSWORD,
+// а также MAIL_TEMPLATES_DIR (каталог, шаблоны из которого заменяют встроенные) и MAIL_DEFAULT_LOCALE (по умолчанию ru).
+func LoadFromEnv(log *logger.Logger) (*Mailer, error) {
+	renderer, err := NewRenderer(os.Getenv("MAIL_TEMPLATES_DIR"), os.Getenv("MAIL_DEFAULT_LOCALE"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := SMTPConfig{
+		Host:     os.Getenv("MAIL_HOST"),
+		Port:     os.Getenv("MAIL_PORT"),
+		From:     os.Getenv("MAIL_FROM"),
+		Password: os.Getenv("MAIL_PASSWORD"),
+	}
+
+	return NewMailer(cfg, renderer, log), nil
+}
+
+// Locale возвращает поддерживаемый язык писем для указанного языка (или язык по умолчанию).
+func (m *Mailer) Locale(locale string) string {
+	return m.renderer.Locale(locale)
+}
+
+// Send собирает письмо из шаблона name на языке locale и асинхронно отправляет его.
+// Ошибка возвращается, только если письмо не удалось собрать; ошибки SMTP записываются в лог.
+func (m *Mailer) Send(to, locale, name string, data any) error {
+	message, err := m.renderer.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+
+	msg, err := message.Bytes(m.smtp.From, to)
+	if err != nil {
+		return err
+	}
+
+	// Настраиваем аутентификацию для отправки почты
+	auth := smtp.PlainAuth("", m.smtp.From, m.smtp.Password, m.smtp.Host)
+	addr := m.smtp.Host + ":" + m.smtp.Port
+
+	go func() {
+		// При ошибке повторяем отправку один раз
+		err := smtp.SendMail(addr, auth, m.smtp.From, []string{to}, msg)
+		if err != nil {
+			err = smtp.SendMail(addr, auth, m.smtp.From, []string{to}, msg)
+		}
+		if err != nil {
+			m.log.Error(fmt.Sprintf("Ошибка отправки письма %s:", name), err)
+		}
+	}()
+
+	return nil
+}
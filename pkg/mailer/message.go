@@ -0,0 +1,123 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var errHeaderInjection = errors.New("заголовок письма содержит перевод строки")
+
+// Message — собранное письмо: тема, текстовая и HTML-версии.
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes формирует письмо в формате MIME (multipart/alternative с текстовой и HTML-версиями) для отправки по SMTP.
+func (m *Message) Bytes(from, to string) ([]byte, error) {
+	// Адреса и тема попадают в заголовки: перевод строки в них позволил бы дописать свои заголовки
+	fromAddr, err := formatAddress(from)
+	if err != nil {
+		return nil, err
+	}
+	toAddr, err := formatAddress(to)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, errHeaderInjection
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", m.Text},
+		{"text/html; charset=UTF-8", m.HTML},
+	}
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(fromAddr.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", fromAddr.String()},
+		{"To", toAddr.String()},
+		{"Subject", mime.BEncoding.Encode("UTF-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary())},
+	}
+	for _, header := range headers {
+		msg.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// formatAddress разбирает адрес ("user@example.com" или "Имя <user@example.com>") для заголовка письма.
+// Адрес с переводом строки отклоняется.
+func formatAddress(value string) (*mail.Address, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return nil, errHeaderInjection
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес %q: %v", value, err)
+	}
+
+	return addr, nil
+}
+
+// newMessageID генерирует уникальный заголовок Message-ID в домене отправителя.
+func newMessageID(from string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain), nil
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMessageBytesRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		subject string
+	}{
+		{"перевод строки в адресе получателя", "noreply@example.com", "user@example.com\r\nBcc: victim@example.com", "Тема"},
+		{"перевод строки в адресе отправителя", "noreply@example.com\nBcc: victim@example.com", "user@example.com", "Тема"},
+		{"перевод строки в теме", "noreply@example.com", "user@example.com", "Тема\r\nBcc: victim@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{Subject: tt.subject, Text: "текст", HTML: "<p>текст</p>"}
+			if _, err := msg.Bytes(tt.from, tt.to); !errors.Is(err, errHeaderInjection) {
+				t.Errorf("ошибка %v, ожидалась errHeaderInjection", err)
+			}
+		})
+	}
+
+	msg := &Message{Subject: "Тема", Text: "текст", HTML: "<p>текст</p>"}
+	if _, err := msg.Bytes("noreply@example.com", "not an address"); err == nil {
+		t.Error("некорректный адрес получателя принят")
+	}
+}
+
+func TestMessageBytesHeaders(t *testing.T) {
+	msg := &Message{Subject: "Тема", Text: "текст", HTML: "<p>текст</p>"}
+
+	raw, err := msg.Bytes("Доставка <noreply@example.com>", "user@example.com")
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+
+	header, _, _ := strings.Cut(string(raw), "\r\n\r\n")
+	for _, want := range []string{
+		"From: =?utf-8?q?=D0=94=D0=BE=D1=81=D1=82=D0=B0=D0=B2=D0=BA=D0=B0?= <noreply@example.com>\r\n",
+		"To: <user@example.com>\r\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("в заголовках нет %q:\n%s", want, header)
+		}
+	}
+
+	// Message-ID в домене адреса отправителя, а не в хвосте строки "Имя <адрес>"
+	if !strings.Contains(header, "@example.com>\r\nMIME-Version") {
+		t.Errorf("Message-ID не в домене отправителя:\n%s", header)
+	}
+}
+
+func TestRendererCachesTemplates(t *testing.T) {
+	renderer, err := NewRenderer("", "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := CodeData{Code: "123456", TTLMinutes: 15}
+	first, err := renderer.Render(TemplateAccountDeletion, "en", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	second, err := renderer.Render(TemplateAccountDeletion, "en-US", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	if len(renderer.cache) != 1 {
+		t.Errorf("в кеше %d шаблонов, ожидался 1", len(renderer.cache))
+	}
+	if *first != *second {
+		t.Error("письма из кешированного шаблона отличаются")
+	}
+}
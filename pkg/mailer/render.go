@@ -0,0 +1,233 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Шаблоны писем лежат в templates/<язык>/:
+//   - <name>.txt  — text/template: тема письма в блоке {{define "subject"}} и текстовая версия письма;
+//   - <name>.html — html/template: блок {{define "content"}} с HTML-версией письма;
+//   - layout.html — общий HTML-макет, в который подставляется блок "content".
+//
+// Встроенные шаблоны можно заменить, положив файл с тем же путём в каталог переопределения.
+// Шаблоны разбираются при первой отправке письма и кешируются: изменения в каталоге переопределения
+// применяются после перезапуска.
+
+//go:embed templates
+var embedded embed.FS
+
+// Имена шаблонов писем.
+const (
+	TemplateConfirmation         = "confirmation"
+	TemplatePasswordReset        = "password_reset"
+	TemplatePasswordChanged      = "password_changed"
+	TemplateEmailChanged         = "email_changed"
+	TemplateEmailChangeRequested = "email_change_requested"
+	TemplateNewSignIn            = "new_sign_in"
+	TemplateOrderReceipt         = "order_receipt"
+	TemplateAccountDeletion      = "account_deletion"
+)
+
+// SupportedLocales — языки, для которых есть встроенные шаблоны.
+var SupportedLocales = []string{"ru", "en"}
+
+var templateFuncs = map[string]any{
+	"money": formatMoney,
+}
+
+// Renderer собирает письма из шаблонов.
+type Renderer struct {
+	overrideDir   string
+	defaultLocale string
+
+	mu    sync.Mutex
+	cache map[string]*parsedTemplate // locale + "/" + name -> разобранные шаблоны письма
+}
+
+// parsedTemplate — разобранные шаблоны одного письма: тема и текст, HTML-версия в макете.
+type parsedTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// NewRenderer создаёт Renderer. overrideDir — каталог с шаблонами, заменяющими встроенные (может быть пустым),
+// defaultLocale — язык писем по умолчанию (пустой — ru).
+func NewRenderer(overrideDir, defaultLocale string) (*Renderer, error) {
+	if defaultLocale == "" {
+		defaultLocale = SupportedLocales[0]
+	}
+	if NormalizeLocale(defaultLocale) == "" {
+		return nil, fmt.Errorf("неподдерживаемый язык писем по умолчанию: %q", defaultLocale)
+	}
+
+	if overrideDir != "" {
+		if info, err := os.Stat(overrideDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("каталог шаблонов писем %q не найден", overrideDir)
+		}
+	}
+
+	return &Renderer{
+		overrideDir:   overrideDir,
+		defaultLocale: NormalizeLocale(defaultLocale),
+		cache:         make(map[string]*parsedTemplate),
+	}, nil
+}
+
+// Locale возвращает поддерживаемый язык для указанного языка или язык по умолчанию.
+func (r *Renderer) Locale(locale string) string {
+	if normalized := NormalizeLocale(locale); normalized != "" {
+		return normalized
+	}
+
+	return r.defaultLocale
+}
+
+// Render собирает письмо из шаблона name на языке locale. Если шаблона на этом языке нет,
+// используется шаблон на языке по умолчанию.
+func (r *Renderer) Render(name, locale string, data any) (*Message, error) {
+	tmpl, err := r.template(name, r.Locale(locale))
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text bytes.Buffer
+	if err = tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("ошибка сборки темы письма %s: %v", name, err)
+	}
+	if err = tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("ошибка сборки письма %s: %v", name, err)
+	}
+
+	var html bytes.Buffer
+	if err = tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("ошибка сборки HTML-версии письма %s: %v", name, err)
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()),
+		HTML:    html.String(),
+	}, nil
+}
+
+// template возвращает разобранные шаблоны письма из кеша, при первом обращении читает и разбирает их.
+// Разобранные шаблоны можно выполнять одновременно из нескольких горутин.
+func (r *Renderer) template(name, locale string) (*parsedTemplate, error) {
+	key := locale + "/" + name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tmpl, ok := r.cache[key]; ok {
+		return tmpl, nil
+	}
+
+	textSource, err := r.readTemplate(locale, name+".txt")
+	if err != nil {
+		return nil, err
+	}
+	textTmpl, err := texttemplate.New(name).Funcs(templateFuncs).Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора шаблона письма %s: %v", name, err)
+	}
+
+	layoutSource, err := r.readTemplate(locale, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+	htmlSource, err := r.readTemplate(locale, name+".html")
+	if err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := htmltemplate.New("layout").Funcs(templateFuncs).Parse(layoutSource)
+	if err == nil {
+		_, err = htmlTmpl.Parse(htmlSource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора HTML-шаблона письма %s: %v", name, err)
+	}
+
+	tmpl := &parsedTemplate{text: textTmpl, html: htmlTmpl}
+	r.cache[key] = tmpl
+
+	return tmpl, nil
+}
+
+// readTemplate читает файл шаблона: сначала из каталога переопределения, затем из встроенных шаблонов.
+// Если файла нет на запрошенном языке, берётся файл на языке по умолчанию.
+func (r *Renderer) readTemplate(locale, file string) (string, error) {
+	locales := []string{locale}
+	if locale != r.defaultLocale {
+		locales = append(locales, r.defaultLocale)
+	}
+
+	for _, loc := range locales {
+		if r.overrideDir != "" {
+			content, err := os.ReadFile(filepath.Join(r.overrideDir, loc, file))
+			if err == nil {
+				return string(content), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("ошибка чтения шаблона письма %s/%s: %v", loc, file, err)
+			}
+		}
+
+		content, err := embedded.ReadFile(path.Join("templates", loc, file))
+		if err == nil {
+			return string(content), nil
+		}
+	}
+
+	return "", fmt.Errorf("шаблон письма %s не найден", file)
+}
+
+// NormalizeLocale приводит язык к поддерживаемому ("en-US" → "en"). Для неподдерживаемого языка возвращает пустую строку.
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+
+	for _, supported := range SupportedLocales {
+		if locale == supported {
+			return supported
+		}
+	}
+
+	return ""
+}
+
+// LocaleFromAcceptLanguage выбирает первый поддерживаемый язык из заголовка Accept-Language
+// (порядок языков в заголовке считается порядком предпочтения). Если подходящего языка нет, возвращает пустую строку.
+func LocaleFromAcceptLanguage(header string) string {
+	for _, item := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(item, ";")
+		if locale := NormalizeLocale(tag); locale != "" {
+			return locale
+		}
+	}
+
+	return ""
+}
+
+// formatMoney форматирует сумму в копейках (центах): 123450 → "1234.50".
+func formatMoney(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
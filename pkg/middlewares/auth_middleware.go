@@ -0,0 +1,131 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/jwtkeys"
+	"food-delivery/pkg/presence"
+	"food-delivery/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"strings"
+)
+
+var (
+	errInvalidAccessToken  = errors.New("невалидный access токен")
+	errInvalidRefreshToken = errors.New("невалидный refresh токен")
+)
+
+// claimKey — ключ, под которым AuthMiddleware кладёт claims access токена в контекст запроса.
+type claimKey struct{}
+
+// ClaimFromContext возвращает claims access токена, проверенного AuthMiddleware.
+func ClaimFromContext(ctx context.Context) (*entities.AccessClaim, bool) {
+	claim, ok := ctx.Value(claimKey{}).(*entities.AccessClaim)
+	return claim, ok
+}
+
+// BearerToken извлекает access токен из заголовка Authorization ("Bearer <token>").
+// Если заголовка нет, возвращается пустая строка.
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+
+	return strings.TrimSpace(header[7:])
+}
+
+// ParseAccessToken проверяет подпись, срок действия и тип access токена и возвращает его claims.
+func ParseAccessToken(accessToken string) (*entities.AccessClaim, error) {
+	claim := &entities.AccessClaim{}
+
+	// Ключ проверки выбирается по kid, алгоритм токена должен совпадать с алгоритмом ключа
+	token, err := jwt.ParseWithClaims(accessToken, claim, jwtkeys.Default().Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, errInvalidAccessToken
+	}
+	// Refresh токен подписан тем же ключом и не должен приниматься вместо access токена
+	if claim.Type != entities.TokenTypeAccess {
+		return nil, errInvalidAccessToken
+	}
+
+	return claim, nil
+}
+
+// ParseSessionRefreshToken проверяет подпись, срок действия и тип refresh токена сессии и возвращает его claims.
+func ParseSessionRefreshToken(refreshToken string) (*entities.RefreshClaim, error) {
+	claim := &entities.RefreshClaim{}
+
+	token, err := jwt.ParseWithClaims(refreshToken, claim, jwtkeys.Default().Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, errInvalidRefreshToken
+	}
+	if claim.Type != entities.TokenTypeRefresh || claim.SessionID == "" {
+		return nil, errInvalidRefreshToken
+	}
+
+	return claim, nil
+}
+
+// AuthMiddleware пропускает только запросы с действительным и не отозванным access токеном
+// и кладёт его claims (ID, Email, Role, SessionID) в контекст запроса.
+func AuthMiddleware(client *redis.Client) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessToken := BearerToken(r)
+			if accessToken == "" {
+				utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+				return
+			}
+
+			claim, err := ParseAccessToken(accessToken)
+			if err != nil {
+				utils.DecodeErr(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			// Проверяем, не был ли токен отозван (выход, блокировка, смена пароля)
+			revoked, err := IsAccessTokenRevoked(r.Context(), client, claim)
+			if err != nil {
+				utils.DecodeErr(w, "произошла внутренняя ошибка", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				utils.DecodeErr(w, "access токен отозван", http.StatusUnauthorized)
+				return
+			}
+
+			// Любой авторизованный запрос отмечает пользователя в сети; ошибка Redis запрос не прерывает
+			_ = presence.Touch(r.Context(), client, claim.ID)
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimKey{}, claim)))
+		})
+	}
+}
+
+// RequireRole пропускает только пользователей с одной из перечисленных ролей.
+// Используется после AuthMiddleware, например: admin.Use(AuthMiddleware(client), RequireRole("admin")).
+func RequireRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claim, ok := ClaimFromContext(r.Context())
+			if !ok {
+				utils.DecodeErr(w, "ошибка авторизации", http.StatusUnauthorized)
+				return
+			}
+
+			for _, role := range roles {
+				if claim.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			utils.DecodeErr(w, "недостаточно прав", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package middlewares
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/jwtkeys"
+	"food-delivery/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testKeys генерирует ключ Ed25519 и делает его активным ключом подписи токенов.
+func testKeys(t *testing.T) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	dir := t.TempDir()
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(filepath.Join(dir, "test.pem"), data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	ring, err := jwtkeys.Load(dir, "test")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	previous := jwtkeys.Default()
+	jwtkeys.SetDefault(ring)
+	t.Cleanup(func() { jwtkeys.SetDefault(previous) })
+}
+
+func testTokens(t *testing.T) (accessToken, refreshToken string) {
+	t.Helper()
+	testKeys(t)
+
+	user := &entities.User{ID: 1, Email: "user@example.com", Role: "user"}
+	accessToken, err := utils.GenerateSessionAccessToken(user, "session-1", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateSessionAccessToken: %v", err)
+	}
+	refreshToken, _, err = utils.GenerateSessionRefreshToken(user, "session-1", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateSessionRefreshToken: %v", err)
+	}
+
+	return accessToken, refreshToken
+}
+
+func TestParseTokensCheckType(t *testing.T) {
+	accessToken, refreshToken := testTokens(t)
+
+	if _, err := ParseAccessToken(accessToken); err != nil {
+		t.Errorf("access токен отклонён: %v", err)
+	}
+	if _, err := ParseAccessToken(refreshToken); err == nil {
+		t.Error("refresh токен принят как access токен")
+	}
+
+	if _, err := ParseSessionRefreshToken(refreshToken); err != nil {
+		t.Errorf("refresh токен отклонён: %v", err)
+	}
+	if _, err := ParseSessionRefreshToken(accessToken); err == nil {
+		t.Error("access токен принят как refresh токен")
+	}
+}
+
+func TestAuthMiddlewareRejectsRefreshToken(t *testing.T) {
+	_, refreshToken := testTokens(t)
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+
+	// Токен отклоняется до проверки отзыва, поэтому Redis не нужен
+	AuthMiddleware(nil)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	if called {
+		t.Error("запрос с refresh токеном прошёл AuthMiddleware")
+	}
+}
@@ -0,0 +1,169 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// clientIPKey — ключ, под которым ClientIPMiddleware кладёт IP-адрес клиента в контекст запроса.
+type clientIPKey struct{}
+
+// ClientIPFromContext возвращает IP-адрес клиента, определённый ClientIPMiddleware
+// (пустую строку, если адрес определить не удалось).
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// ParseTrustedProxies разбирает список доверенных прокси через запятую: подсети в нотации CIDR
+// или отдельные IP-адреса (например, "10.0.0.0/8, 127.0.0.1, ::1").
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("некорректная подсеть доверенного прокси %q: %w", item, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный адрес доверенного прокси %q: %w", item, err)
+		}
+		addr = addr.WithZone("").Unmap()
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return proxies, nil
+}
+
+// Заголовки, из которых можно брать цепочку адресов клиента.
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded"
+)
+
+// ParseForwardedHeader проверяет имя заголовка, который записывает доверенный прокси:
+// X-Forwarded-For (по умолчанию, если имя пустое) или Forwarded (RFC 7239).
+func ParseForwardedHeader(name string) (string, error) {
+	switch header := http.CanonicalHeaderKey(strings.TrimSpace(name)); header {
+	case "":
+		return HeaderXForwardedFor, nil
+	case HeaderXForwardedFor, HeaderForwarded:
+		return header, nil
+	default:
+		return "", fmt.Errorf("неподдерживаемый заголовок прокси %q: ожидается %s или %s", name, HeaderXForwardedFor, HeaderForwarded)
+	}
+}
+
+// ClientIPMiddleware определяет реальный IP-адрес клиента и кладёт его в контекст запроса.
+// Цепочка адресов берётся только из заголовка header — того, который записывает доверенный прокси
+// (см. ParseForwardedHeader): другой заголовок прокси передаёт без изменений, и его мог задать сам клиент.
+// Заголовок учитывается, только если запрос пришёл от доверенного прокси: цепочка адресов просматривается
+// справа налево, и клиентом считается первый адрес, не являющийся доверенным прокси.
+// Соединение через Unix-сокет (без IP-адреса) считается соединением от локального прокси.
+func ClientIPMiddleware(trusted []netip.Prefix, header string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r, trusted, header)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+func clientIP(r *http.Request, trusted []netip.Prefix, header string) string {
+	remote, ok := parseAddr(r.RemoteAddr)
+	if ok && !isTrusted(remote, trusted) {
+		return remote.String()
+	}
+
+	// Запрос пришёл от доверенного прокси: идём по цепочке адресов от ближнего прокси к клиенту
+	chain := forwardedChain(r, header)
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, valid := parseAddr(chain[i])
+		if !valid {
+			// Адрес скрыт ("unknown", обфусцированный идентификатор): дальше по цепочке идти нельзя
+			break
+		}
+		if !isTrusted(addr, trusted) || i == 0 {
+			return addr.String()
+		}
+	}
+
+	if ok {
+		return remote.String()
+	}
+
+	return ""
+}
+
+// forwardedChain возвращает цепочку адресов из заголовка header: Forwarded (RFC 7239) или X-Forwarded-For.
+// Первый адрес — клиент, последний — ближайший к нам прокси.
+func forwardedChain(r *http.Request, header string) []string {
+	var chain []string
+
+	if header == HeaderForwarded {
+		for _, value := range r.Header.Values(HeaderForwarded) {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+					if found && strings.EqualFold(key, "for") {
+						chain = append(chain, strings.Trim(val, `"`))
+					}
+				}
+			}
+		}
+		return chain
+	}
+
+	for _, value := range r.Header.Values(HeaderXForwardedFor) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				chain = append(chain, item)
+			}
+		}
+	}
+
+	return chain
+}
+
+// parseAddr разбирает IP-адрес с портом или без него, в том числе IPv6 в квадратных скобках
+// и с идентификатором зоны. Зона отбрасывается, IPv4-mapped IPv6 приводится к IPv4.
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.WithZone("").Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
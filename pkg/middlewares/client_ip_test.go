@@ -0,0 +1,207 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 127.0.0.1, ::1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		header  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "недоверенный адрес с подделанным X-Forwarded-For",
+			remote:  "203.0.113.5:51000",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "198.51.100.7"},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "недоверенный адрес с подделанным Forwarded",
+			remote:  "203.0.113.5:51000",
+			header:  HeaderForwarded,
+			headers: map[string]string{HeaderForwarded: "for=198.51.100.7"},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "один доверенный прокси",
+			remote:  "10.0.0.1:443",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "несколько доверенных прокси",
+			remote:  "10.0.0.1:443",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "198.51.100.7, 10.0.0.3, 10.0.0.2"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "адрес, подставленный клиентом в начало цепочки",
+			remote:  "10.0.0.1:443",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "1.1.1.1, 198.51.100.7, 10.0.0.2"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "вся цепочка из доверенных адресов",
+			remote:  "10.0.0.1:443",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "10.0.0.5, 10.0.0.2"},
+			want:    "10.0.0.5",
+		},
+		{
+			name:    "Forwarded с IPv6 в кавычках и с портом",
+			remote:  "[::1]:443",
+			header:  HeaderForwarded,
+			headers: map[string]string{HeaderForwarded: `for="[2001:db8::1]:4711";proto=https`},
+			want:    "2001:db8::1",
+		},
+		{
+			name:    "Forwarded с несколькими элементами",
+			remote:  "10.0.0.1:443",
+			header:  HeaderForwarded,
+			headers: map[string]string{HeaderForwarded: "for=198.51.100.7;proto=https, For=10.0.0.2"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:    "Forwarded с for=unknown",
+			remote:  "10.0.0.1:443",
+			header:  HeaderForwarded,
+			headers: map[string]string{HeaderForwarded: "for=198.51.100.7, for=unknown"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "X-Forwarded-For не учитывается, если настроен Forwarded",
+			remote:  "10.0.0.1:443",
+			header:  HeaderForwarded,
+			headers: map[string]string{HeaderXForwardedFor: "198.51.100.7"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "Forwarded не учитывается, если настроен X-Forwarded-For",
+			remote:  "10.0.0.1:443",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderForwarded: "for=198.51.100.7"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "Unix-сокет с X-Forwarded-For",
+			remote:  "@",
+			header:  HeaderXForwardedFor,
+			headers: map[string]string{HeaderXForwardedFor: "198.51.100.7"},
+			want:    "198.51.100.7",
+		},
+		{
+			name:   "Unix-сокет без заголовка",
+			remote: "@",
+			header: HeaderXForwardedFor,
+			want:   "",
+		},
+		{
+			name:   "IPv4-mapped IPv6",
+			remote: "[::ffff:203.0.113.5]:51000",
+			header: HeaderXForwardedFor,
+			want:   "203.0.113.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			if got := clientIP(req, trusted, tt.header); got != tt.want {
+				t.Errorf("clientIP() = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		want   []string
+	}{
+		{
+			name:   "X-Forwarded-For в нескольких заголовках",
+			header: HeaderXForwardedFor,
+			values: []string{"198.51.100.7, 10.0.0.3", " ,10.0.0.2"},
+			want:   []string{"198.51.100.7", "10.0.0.3", "10.0.0.2"},
+		},
+		{
+			name:   "Forwarded с кавычками и другими параметрами",
+			header: HeaderForwarded,
+			values: []string{`for="[2001:db8::1]:4711";proto=https;by=10.0.0.2, for=unknown`},
+			want:   []string{"[2001:db8::1]:4711", "unknown"},
+		},
+		{
+			name:   "Forwarded без параметра for",
+			header: HeaderForwarded,
+			values: []string{"proto=https;host=example.com"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, value := range tt.values {
+				req.Header.Add(tt.header, value)
+			}
+
+			if got := forwardedChain(req, tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("forwardedChain() = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{value: "198.51.100.7", want: "198.51.100.7", ok: true},
+		{value: "198.51.100.7:8080", want: "198.51.100.7", ok: true},
+		{value: "2001:db8::1", want: "2001:db8::1", ok: true},
+		{value: "[2001:db8::1]", want: "2001:db8::1", ok: true},
+		{value: "[2001:db8::1]:4711", want: "2001:db8::1", ok: true},
+		{value: "[fe80::1%eth0]:443", want: "fe80::1", ok: true},
+		{value: "::ffff:203.0.113.5", want: "203.0.113.5", ok: true},
+		{value: "unknown", ok: false},
+		{value: "_hidden", ok: false},
+		{value: "@", ok: false},
+		{value: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			addr, ok := parseAddr(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("parseAddr(%q) ok = %v, ожидалось %v", tt.value, ok, tt.ok)
+			}
+			if ok && addr.String() != tt.want {
+				t.Errorf("parseAddr(%q) = %q, ожидалось %q", tt.value, addr.String(), tt.want)
+			}
+		})
+	}
+}
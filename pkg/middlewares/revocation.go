@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"food-delivery/internal/auth/entities"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// Ключи Redis для отзыва access токенов:
+//   - revoked:jti:<jti>          — отозван конкретный токен;
+//   - revoked:session:<sid>      — отозваны все токены сессии (устройства);
+//   - revoked:user:<id>          — отозваны все токены пользователя, выпущенные не позже сохранённого времени
+//     (в миллисекундах).
+//
+// Все ключи живут не дольше срока действия access токена: после этого отозванные токены истекают сами.
+
+func revokedJTIKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+func revokedSessionKey(sessionID string) string {
+	return "revoked:session:" + sessionID
+}
+
+func revokedUserKey(userID int) string {
+	return fmt.Sprintf("revoked:user:%d", userID)
+}
+
+// RevokeAccessToken добавляет jti access токена в denylist до истечения срока его действия.
+func RevokeAccessToken(ctx context.Context, client *redis.Client, claim *entities.AccessClaim) error {
+	if claim.RegisteredClaims.ID == "" || claim.ExpiresAt == nil {
+		return nil
+	}
+
+	ttl := time.Until(claim.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil // Токен уже истёк
+	}
+
+	return client.Set(ctx, revokedJTIKey(claim.RegisteredClaims.ID), 1, ttl).Err()
+}
+
+// RevokeSessionAccessTokens отзывает все access токены, выпущенные в рамках сессии.
+// ttl должен быть не меньше времени жизни access токена.
+func RevokeSessionAccessTokens(ctx context.Context, client *redis.Client, sessionID string, ttl time.Duration) error {
+	return client.Set(ctx, revokedSessionKey(sessionID), 1, ttl).Err()
+}
+
+// RevokeUserAccessTokens отзывает все access токены пользователя, выпущенные до текущего момента.
+// ttl должен быть не меньше времени жизни access токена.
+func RevokeUserAccessTokens(ctx context.Context, client *redis.Client, userID int, ttl time.Duration) error {
+	return client.Set(ctx, revokedUserKey(userID), time.Now().UnixMilli(), ttl).Err()
+}
+
+// IsAccessTokenRevoked проверяет, был ли access токен отозван любым из способов.
+func IsAccessTokenRevoked(ctx context.Context, client *redis.Client, claim *entities.AccessClaim) (bool, error) {
+	keys := []string{revokedJTIKey(claim.RegisteredClaims.ID), revokedUserKey(claim.ID)}
+	if claim.SessionID != "" {
+		keys = append(keys, revokedSessionKey(claim.SessionID))
+	}
+
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+
+	// Отозван конкретный токен
+	if values[0] != nil {
+		return true, nil
+	}
+
+	// Отозваны все токены сессии
+	if len(values) > 2 && values[2] != nil {
+		return true, nil
+	}
+
+	// Отозваны все токены пользователя, выпущенные не позже сохранённого времени
+	if revokedBefore, ok := values[1].(string); ok {
+		revokedMs, err := strconv.ParseInt(revokedBefore, 10, 64)
+		if err != nil {
+			return false, err
+		}
+
+		issuedMs, ok := issuedAtMs(claim)
+		if !ok || issuedMs <= revokedMs {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// issuedAtMs возвращает время выпуска токена в миллисекундах. Для токенов, выпущенных до появления
+// iat_ms, используется начало секунды из iat: такой токен, выпущенный в секунду отзыва, считается отозванным.
+func issuedAtMs(claim *entities.AccessClaim) (int64, bool) {
+	if claim.IssuedAtMs > 0 {
+		return claim.IssuedAtMs, true
+	}
+	if claim.IssuedAt == nil {
+		return 0, false
+	}
+
+	return claim.IssuedAt.Unix() * 1000, true
+}
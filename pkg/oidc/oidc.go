@@ -0,0 +1,229 @@
+// Package oidc реализует универсальный клиент OpenID Connect (authorization code flow с PKCE)
+// для входа через внешних провайдеров (Google, Apple, Yandex и т.п.).
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDiscovery    = errors.New("не удалось получить конфигурацию OIDC провайдера")
+	ErrExchange     = errors.New("не удалось обменять код авторизации на токены")
+	ErrInvalidToken = errors.New("невалидный ID токен")
+)
+
+// Config описывает настройки одного OIDC провайдера.
+type Config struct {
+	Name         string   // Имя провайдера в URL (google, apple, yandex)
+	Issuer       string   // Issuer провайдера, по нему выполняется discovery
+	ClientID     string   // ID клиента, выданный провайдером
+	ClientSecret string   // Секрет клиента
+	RedirectURL  string   // Адрес callback-эндпоинта приложения
+	Scopes       []string // Запрашиваемые scope (openid добавляется всегда)
+}
+
+// Metadata — нужная часть документа /.well-known/openid-configuration.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse — ответ token endpoint провайдера.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Client выполняет вход через одного OIDC провайдера.
+// Конфигурация провайдера и его ключи загружаются при первом обращении и кэшируются.
+type Client struct {
+	cfg  Config
+	http *http.Client
+
+	mu          sync.Mutex
+	meta        *Metadata
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// NewClient создаёт клиента провайдера. Если httpClient равен nil, используется клиент с таймаутом 10 секунд.
+// Issuer может быть и http-адресом, что позволяет работать с локальным mock-провайдером.
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Client{cfg: cfg, http: httpClient}
+}
+
+// Name возвращает имя провайдера.
+func (c *Client) Name() string {
+	return c.cfg.Name
+}
+
+// LoadClients создаёт клиентов для провайдеров, перечисленных в OIDC_PROVIDERS (через запятую).
+// Настройки провайдера берутся из переменных OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL и _SCOPES (через пробел). Провайдеры без issuer или client id пропускаются.
+func LoadClients(httpClient *http.Client) map[string]*Client {
+	clients := make(map[string]*Client)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			continue
+		}
+
+		clients[name] = NewClient(cfg, httpClient)
+	}
+
+	return clients
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера с параметрами state, nonce и PKCE (S256).
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range c.cfg.Scopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(c.cfg.Scopes) == 0 {
+		scopes = append(scopes, "email", "profile")
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange обменивает код авторизации на токены провайдера.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (*TokenResponse, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"client_id":     {c.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens TokenResponse
+	if err = c.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: провайдер не вернул id_token", ErrExchange)
+	}
+
+	return &tokens, nil
+}
+
+// discover загружает и кэширует конфигурацию провайдера.
+func (c *Client) discover(ctx context.Context) (*Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.meta != nil {
+		return c.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+
+	var meta Metadata
+	if err = c.doJSON(req, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+
+	// Issuer из документа должен совпадать с настроенным (OpenID Connect Discovery, раздел 4.3)
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(c.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("%w: issuer %q не совпадает с настроенным", ErrDiscovery, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: в конфигурации не хватает эндпоинтов", ErrDiscovery)
+	}
+
+	c.meta = &meta
+	return c.meta, nil
+}
+
+// doJSON выполняет запрос и декодирует JSON-ответ. Ответ с кодом, отличным от 2xx, считается ошибкой.
+func (c *Client) doJSON(req *http.Request, v interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s ответил %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,269 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"food-delivery/pkg/oidc"
+	"food-delivery/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v4"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const redirectURL = "http://app.test/api/auth/oidc/mock/callback"
+
+func newProvider(t *testing.T) *oidctest.Provider {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider("food-delivery")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	t.Cleanup(provider.Close)
+
+	provider.SetUser(oidc.IDTokenClaims{
+		Email:         "user@example.com",
+		EmailVerified: true,
+		GivenName:     "Иван",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: "mock-subject-1",
+		},
+	})
+
+	return provider
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	provider := newProvider(t)
+	client := provider.Client("mock", redirectURL)
+
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, provider.Issuer()+"/authorize?") {
+		t.Errorf("адрес авторизации %q не ведёт на authorization_endpoint провайдера", authURL)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "food-delivery",
+		"redirect_uri":          redirectURL,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        oidc.CodeChallenge(verifier),
+		"code_challenge_method": "S256",
+	}
+	for param, value := range want {
+		if got := query.Get(param); got != value {
+			t.Errorf("%s = %q, ожидалось %q", param, got, value)
+		}
+	}
+	if scopes := strings.Fields(query.Get("scope")); len(scopes) == 0 || scopes[0] != "openid" {
+		t.Errorf("scope = %q, ожидался openid первым", query.Get("scope"))
+	}
+	if query.Get("code_challenge") == verifier {
+		t.Error("в адресе передан сам code verifier вместо challenge")
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := oidc.CodeChallenge(verifier); got != challenge {
+		t.Errorf("CodeChallenge = %q, ожидалось %q", got, challenge)
+	}
+}
+
+// signIn проходит вход у провайдера и возвращает code, state и verifier.
+func signIn(t *testing.T, provider *oidctest.Provider, client *oidc.Client, state, nonce string) (string, string, string) {
+	t.Helper()
+
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	code, returnedState, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	return code, returnedState, verifier
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	provider := newProvider(t)
+	client := provider.Client("mock", redirectURL)
+	ctx := context.Background()
+
+	code, state, verifier := signIn(t, provider, client, "state-1", "nonce-1")
+	if state != "state-1" {
+		t.Fatalf("провайдер вернул state %q, ожидался state-1", state)
+	}
+
+	tokens, err := client.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	claims, err := client.VerifyIDToken(ctx, tokens.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "mock-subject-1" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Errorf("неожиданные данные пользователя: sub=%q email=%q verified=%v", claims.Subject, claims.Email, claims.EmailVerified)
+	}
+
+	// Код авторизации одноразовый
+	if _, err = client.Exchange(ctx, code, verifier); !errors.Is(err, oidc.ErrExchange) {
+		t.Errorf("повторный обмен кода: ошибка %v, ожидалась ErrExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider := newProvider(t)
+	client := provider.Client("mock", redirectURL)
+
+	code, _, _ := signIn(t, provider, client, "state-1", "nonce-1")
+
+	otherVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Exchange(context.Background(), code, otherVerifier); !errors.Is(err, oidc.ErrExchange) {
+		t.Errorf("обмен с чужим code verifier: ошибка %v, ожидалась ErrExchange", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsNonce(t *testing.T) {
+	provider := newProvider(t)
+	client := provider.Client("mock", redirectURL)
+	ctx := context.Background()
+
+	code, _, verifier := signIn(t, provider, client, "state-1", "nonce-1")
+
+	tokens, err := client.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	// Токен, выпущенный для другого входа (другой nonce), не принимается
+	if _, err = client.VerifyIDToken(ctx, tokens.IDToken, "nonce-2"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("чужой nonce: ошибка %v, ожидалась ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsClaims(t *testing.T) {
+	provider := newProvider(t)
+	client := provider.Client("mock", redirectURL)
+
+	user := oidc.IDTokenClaims{
+		Email:            "user@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "mock-subject-1"},
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims *oidc.IDTokenClaims)
+		sign   func(claims oidc.IDTokenClaims) (string, error)
+	}{
+		{
+			name:   "чужой issuer",
+			modify: func(claims *oidc.IDTokenClaims) { claims.Issuer = "https://evil.example.com" },
+		},
+		{
+			name:   "другой клиент (aud)",
+			modify: func(claims *oidc.IDTokenClaims) { claims.Audience = jwt.ClaimStrings{"other-client"} },
+		},
+		{
+			name: "истёкший токен",
+			modify: func(claims *oidc.IDTokenClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			},
+		},
+		{
+			name:   "без exp",
+			modify: func(claims *oidc.IDTokenClaims) { claims.ExpiresAt = nil },
+		},
+		{
+			name:   "без sub",
+			modify: func(claims *oidc.IDTokenClaims) { claims.Subject = "" },
+		},
+		{
+			name: "подпись чужим ключом",
+			sign: func(claims oidc.IDTokenClaims) (string, error) {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = "mock-1"
+				return token.SignedString(otherKey)
+			},
+		},
+		{
+			name: "симметричная подпись",
+			sign: func(claims oidc.IDTokenClaims) (string, error) {
+				return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := provider.IDTokenClaims(user, "nonce-1")
+			if tt.modify != nil {
+				tt.modify(&claims)
+			}
+
+			sign := provider.SignIDToken
+			if tt.sign != nil {
+				sign = func(c jwt.Claims) (string, error) { return tt.sign(*c.(*oidc.IDTokenClaims)) }
+			}
+
+			rawToken, err := sign(&claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = client.VerifyIDToken(context.Background(), rawToken, "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("ошибка %v, ожидалась ErrInvalidToken", err)
+			}
+		})
+	}
+
+	// Контрольный случай: без изменений токен принимается
+	claims := provider.IDTokenClaims(user, "nonce-1")
+	rawToken, err := provider.SignIDToken(&claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.VerifyIDToken(context.Background(), rawToken, "nonce-1"); err != nil {
+		t.Errorf("корректный токен отклонён: %v", err)
+	}
+}
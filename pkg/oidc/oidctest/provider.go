@@ -0,0 +1,277 @@
+// Package oidctest реализует локальный mock OIDC провайдера для тестов: discovery, JWKS,
+// страницу авторизации и token endpoint с проверкой PKCE (S256).
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food-delivery/pkg/oidc"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// IDTokenTTL — время жизни ID токенов, выпускаемых провайдером.
+const IDTokenTTL = 5 * time.Minute
+
+// Provider — OIDC провайдер на httptest.Server. Пользователь, который «входит» у провайдера,
+// задаётся через SetUser; ID токен выпускается для него с nonce из запроса авторизации.
+type Provider struct {
+	ClientID string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	user  oidc.IDTokenClaims
+	codes map[string]authRequest
+}
+
+// authRequest — выданный, но ещё не обменянный код авторизации.
+type authRequest struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	user          oidc.IDTokenClaims
+}
+
+// NewProvider запускает провайдера для клиента clientID. Провайдер нужно остановить вызовом Close.
+func NewProvider(clientID string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		ClientID: clientID,
+		key:      key,
+		kid:      "mock-1",
+		codes:    make(map[string]authRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+// Close останавливает провайдера.
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// Issuer возвращает issuer провайдера (адрес тестового сервера).
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// Client создаёт клиента приложения, настроенного на этого провайдера.
+func (p *Provider) Client(name, redirectURL string) *oidc.Client {
+	return oidc.NewClient(oidc.Config{
+		Name:        name,
+		Issuer:      p.Issuer(),
+		ClientID:    p.ClientID,
+		RedirectURL: redirectURL,
+	}, p.server.Client())
+}
+
+// SetUser задаёт пользователя, для которого выпускаются следующие коды авторизации
+// (sub, email, email_verified, имя). iss, aud, exp, iat и nonce заполняет провайдер.
+func (p *Provider) SetUser(user oidc.IDTokenClaims) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.user = user
+}
+
+// Authorize проходит страницу входа провайдера по адресу authURL (как браузер пользователя)
+// и возвращает code и state из перенаправления на redirect_uri.
+func (p *Provider) Authorize(authURL string) (string, string, error) {
+	client := *p.server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return "", "", fmt.Errorf("провайдер ответил %d вместо перенаправления", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	query := location.Query()
+
+	return query.Get("code"), query.Get("state"), nil
+}
+
+// SignIDToken подписывает произвольные claims ключом провайдера (с его kid).
+// Используется для проверки отказа в токенах с неверными iss, aud, exp и т.п.
+func (p *Provider) SignIDToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+
+	return token.SignedString(p.key)
+}
+
+// IDTokenClaims возвращает claims корректного ID токена для пользователя user и nonce.
+func (p *Provider) IDTokenClaims(user oidc.IDTokenClaims, nonce string) oidc.IDTokenClaims {
+	now := time.Now()
+
+	claims := user
+	claims.Nonce = nonce
+	claims.Issuer = p.Issuer()
+	claims.Audience = jwt.ClaimStrings{p.ClientID}
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(IDTokenTTL))
+
+	return claims
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                p.Issuer(),
+		AuthorizationEndpoint: p.Issuer() + "/authorize",
+		TokenEndpoint:         p.Issuer() + "/token",
+		JWKSURI:               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := p.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": p.kid,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize сразу «входит» текущим пользователем и перенаправляет на redirect_uri с кодом и state.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	switch {
+	case query.Get("response_type") != "code":
+		http.Error(w, "unsupported_response_type", http.StatusBadRequest)
+		return
+	case query.Get("client_id") != p.ClientID:
+		http.Error(w, "unauthorized_client", http.StatusBadRequest)
+		return
+	case query.Get("redirect_uri") == "":
+		http.Error(w, "invalid_request: redirect_uri", http.StatusBadRequest)
+		return
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		http.Error(w, "invalid_request: PKCE S256 обязателен", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oidc.RandomString(16)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authRequest{
+		redirectURI:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          p.user,
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid_request: redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token обменивает одноразовый код на ID токен, проверяя redirect_uri и code_verifier.
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	req, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code")) // Код одноразовый
+	p.mu.Unlock()
+
+	err := checkTokenRequest(r.PostForm, req, ok, p.ClientID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": err.Error()})
+		return
+	}
+
+	idToken, err := p.SignIDToken(p.IDTokenClaims(req.user, req.nonce))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oidc.TokenResponse{
+		AccessToken: "mock-access-token",
+		TokenType:   "Bearer",
+		IDToken:     idToken,
+	})
+}
+
+func checkTokenRequest(form url.Values, req authRequest, found bool, clientID string) error {
+	switch {
+	case form.Get("grant_type") != "authorization_code":
+		return errors.New("неподдерживаемый grant_type")
+	case form.Get("client_id") != clientID:
+		return errors.New("неверный client_id")
+	case !found:
+		return errors.New("код не найден или уже использован")
+	case form.Get("redirect_uri") != req.redirectURI:
+		return errors.New("redirect_uri не совпадает с указанным при авторизации")
+	case oidc.CodeChallenge(form.Get("code_verifier")) != req.codeChallenge:
+		return errors.New("code_verifier не соответствует code_challenge")
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
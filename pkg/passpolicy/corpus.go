@@ -0,0 +1,182 @@
+package passpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Формат файла базы утёкших паролей: заголовок из сигнатуры corpusMagic и одного байта с длиной префикса,
+// за которым без разделителей идут отсортированные по возрастанию префиксы SHA-1 хешей паролей.
+// Префикса в 10 байт достаточно, чтобы ложные совпадения практически не встречались,
+// а файл получается вдвое меньше, чем с полными хешами.
+const (
+	corpusMagic         = "PWSHA1v1"
+	corpusHeaderSize    = len(corpusMagic) + 1
+	DefaultCorpusPrefix = 10
+)
+
+var (
+	errCorpusFormat   = errors.New("некорректный формат файла базы утёкших паролей")
+	errCorpusUnsorted = errors.New("хеши для базы утёкших паролей должны быть отсортированы по возрастанию")
+)
+
+// Corpus — база утёкших паролей на диске. Поиск выполняется двоичным поиском прямо по файлу,
+// без загрузки его в память. Методы безопасны для одновременного использования.
+type Corpus struct {
+	file       *os.File
+	prefixSize int
+	count      int64
+}
+
+// OpenCorpus открывает файл базы утёкших паролей, созданный CorpusWriter.
+func OpenCorpus(path string) (*Corpus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть базу утёкших паролей: %v", err)
+	}
+
+	corpus, err := newCorpus(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return corpus, nil
+}
+
+func newCorpus(file *os.File) (*Corpus, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, corpusHeaderSize)
+	if _, err = file.ReadAt(header, 0); err != nil {
+		return nil, errCorpusFormat
+	}
+
+	prefixSize := int(header[len(corpusMagic)])
+	if string(header[:len(corpusMagic)]) != corpusMagic || prefixSize < 4 || prefixSize > sha1.Size {
+		return nil, errCorpusFormat
+	}
+
+	body := info.Size() - int64(corpusHeaderSize)
+	if body%int64(prefixSize) != 0 {
+		return nil, errCorpusFormat
+	}
+
+	return &Corpus{
+		file:       file,
+		prefixSize: prefixSize,
+		count:      body / int64(prefixSize),
+	}, nil
+}
+
+// Contains сообщает, есть ли пароль в базе утёкших паролей.
+func (c *Corpus) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	return c.containsHash(sum[:c.prefixSize])
+}
+
+func (c *Corpus) containsHash(prefix []byte) (bool, error) {
+	record := make([]byte, c.prefixSize)
+	var readErr error
+
+	// Ищем первую запись, не меньшую искомого префикса
+	idx := sort.Search(int(c.count), func(i int) bool {
+		if readErr != nil {
+			return true
+		}
+		if _, err := c.file.ReadAt(record, int64(corpusHeaderSize)+int64(i)*int64(c.prefixSize)); err != nil {
+			readErr = err
+			return true
+		}
+		return bytes.Compare(record, prefix) >= 0
+	})
+	if readErr != nil {
+		return false, readErr
+	}
+	if idx == int(c.count) {
+		return false, nil
+	}
+
+	if _, err := c.file.ReadAt(record, int64(corpusHeaderSize)+int64(idx)*int64(c.prefixSize)); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(record, prefix), nil
+}
+
+// Close закрывает файл базы.
+func (c *Corpus) Close() error {
+	return c.file.Close()
+}
+
+// CorpusWriter записывает базу утёкших паролей из SHA-1 хешей, поступающих в порядке возрастания
+// (в таком порядке распространяются дампы Have I Been Pwned). Повторяющиеся префиксы пропускаются.
+type CorpusWriter struct {
+	w          *bufio.Writer
+	prefixSize int
+	last       []byte
+	count      int64
+}
+
+func NewCorpusWriter(w io.Writer, prefixSize int) (*CorpusWriter, error) {
+	if prefixSize < 4 || prefixSize > sha1.Size {
+		return nil, fmt.Errorf("длина префикса должна быть от 4 до %d байт", sha1.Size)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(corpusMagic); err != nil {
+		return nil, err
+	}
+	if err := bw.WriteByte(byte(prefixSize)); err != nil {
+		return nil, err
+	}
+
+	return &CorpusWriter{
+		w:          bw,
+		prefixSize: prefixSize,
+	}, nil
+}
+
+// Add добавляет SHA-1 хеш пароля. Хеши должны добавляться в порядке возрастания.
+func (cw *CorpusWriter) Add(hash []byte) error {
+	if len(hash) != sha1.Size {
+		return fmt.Errorf("длина SHA-1 хеша должна быть %d байт", sha1.Size)
+	}
+
+	prefix := hash[:cw.prefixSize]
+	if cw.last != nil {
+		switch bytes.Compare(prefix, cw.last) {
+		case -1:
+			return errCorpusUnsorted
+		case 0:
+			return nil
+		}
+	}
+
+	if _, err := cw.w.Write(prefix); err != nil {
+		return err
+	}
+	cw.last = append(cw.last[:0], prefix...)
+	cw.count++
+
+	return nil
+}
+
+// Count возвращает число записанных префиксов.
+func (cw *CorpusWriter) Count() int64 {
+	return cw.count
+}
+
+// Flush дописывает буферизованные данные.
+func (cw *CorpusWriter) Flush() error {
+	return cw.w.Flush()
+}
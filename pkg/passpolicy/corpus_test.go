@@ -0,0 +1,270 @@
+package passpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Пароли из testdata/pwned-passwords.txt (формат дампа Have I Been Pwned: "SHA1:количество", по возрастанию хеша).
+// Kx9#mQ2$vL7!pR — стойкий пароль с наименьшим хешем в файле, iloveyou — с наибольшим.
+var fixturePasswords = []string{"Kx9#mQ2$vL7!pR", "password", "123456", "qwerty", "letmein", "iloveyou"}
+
+// fixtureHashes читает SHA-1 хеши из файла-фикстуры.
+func fixtureHashes(t *testing.T) [][]byte {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", "pwned-passwords.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var hashes [][]byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, _, _ := strings.Cut(scanner.Text(), ":")
+		hash, err := hex.DecodeString(value)
+		if err != nil {
+			t.Fatalf("некорректная строка фикстуры %q: %v", scanner.Text(), err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return hashes
+}
+
+// buildCorpus записывает хеши в файл базы во временном каталоге и открывает его.
+func buildCorpus(t *testing.T, hashes [][]byte, prefixSize int) *Corpus {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, err := NewCorpusWriter(&buf, prefixSize)
+	if err != nil {
+		t.Fatalf("NewCorpusWriter: %v", err)
+	}
+	for _, hash := range hashes {
+		if err = writer.Add(hash); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.bin")
+	if err = os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	corpus, err := OpenCorpus(path)
+	if err != nil {
+		t.Fatalf("OpenCorpus: %v", err)
+	}
+	t.Cleanup(func() { corpus.Close() })
+
+	return corpus
+}
+
+func TestCorpusContains(t *testing.T) {
+	hashes := fixtureHashes(t)
+
+	for _, prefixSize := range []int{4, DefaultCorpusPrefix, sha1.Size} {
+		corpus := buildCorpus(t, hashes, prefixSize)
+		if corpus.count != int64(len(hashes)) {
+			t.Fatalf("префикс %d: в базе %d записей, ожидалось %d", prefixSize, corpus.count, len(hashes))
+		}
+
+		tests := []struct {
+			password string
+			want     bool
+		}{
+			{"Kx9#mQ2$vL7!pR", true}, // Первая запись
+			{"password", true},
+			{"qwerty", true},
+			{"iloveyou", true}, // Последняя запись
+			{"Password", false},
+			{"correct horse battery staple", false},
+			{"", false},
+		}
+
+		for _, tt := range tests {
+			found, err := corpus.Contains(tt.password)
+			if err != nil {
+				t.Fatalf("префикс %d, %q: %v", prefixSize, tt.password, err)
+			}
+			if found != tt.want {
+				t.Errorf("префикс %d: Contains(%q) = %v, ожидалось %v", prefixSize, tt.password, found, tt.want)
+			}
+		}
+	}
+}
+
+func TestCorpusBoundaries(t *testing.T) {
+	corpus := buildCorpus(t, fixtureHashes(t), DefaultCorpusPrefix)
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   bool
+	}{
+		{"меньше первой записи", "00000000000000000000", false},
+		{"больше последней записи", "FFFFFFFFFFFFFFFFFFFF", false},
+		{"между записями", "5BAA61E4C9B93F3F0683", false},
+		{"отличается последним байтом", "5BAA61E4C9B93F3F0681", false},
+		{"совпадает", "5BAA61E4C9B93F3F0682", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, err := hex.DecodeString(tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found, err := corpus.containsHash(prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.want {
+				t.Errorf("containsHash = %v, ожидалось %v", found, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyCorpus(t *testing.T) {
+	corpus := buildCorpus(t, nil, DefaultCorpusPrefix)
+
+	found, err := corpus.Contains("password")
+	if err != nil || found {
+		t.Errorf("пустая база: Contains = %v, ошибка %v", found, err)
+	}
+}
+
+func TestOpenCorpusRejectsInvalidFile(t *testing.T) {
+	header := corpusMagic + string(rune(DefaultCorpusPrefix))
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"пустой файл", ""},
+		{"чужая сигнатура", "PWSHA1v0" + string(rune(DefaultCorpusPrefix))},
+		{"слишком короткий префикс", corpusMagic + "\x03"},
+		{"префикс длиннее SHA-1", corpusMagic + "\x15"},
+		{"неполная запись", header + strings.Repeat("a", DefaultCorpusPrefix+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breached.bin")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			corpus, err := OpenCorpus(path)
+			if !errors.Is(err, errCorpusFormat) {
+				if corpus != nil {
+					corpus.Close()
+				}
+				t.Errorf("ошибка %v, ожидалась errCorpusFormat", err)
+			}
+		})
+	}
+
+	if _, err := OpenCorpus(filepath.Join(t.TempDir(), "missing.bin")); err == nil {
+		t.Error("отсутствующий файл открыт без ошибки")
+	}
+}
+
+func TestCorpusWriter(t *testing.T) {
+	hashes := fixtureHashes(t)
+
+	t.Run("хеши не по порядку", func(t *testing.T) {
+		writer, err := NewCorpusWriter(&bytes.Buffer{}, DefaultCorpusPrefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Add(hashes[1]); err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Add(hashes[0]); !errors.Is(err, errCorpusUnsorted) {
+			t.Errorf("ошибка %v, ожидалась errCorpusUnsorted", err)
+		}
+	})
+
+	t.Run("повторяющиеся префиксы пропускаются", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer, err := NewCorpusWriter(&buf, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Хеши с одинаковыми первыми 4 байтами дают одну запись
+		same := append([]byte(nil), hashes[1]...)
+		same[sha1.Size-1]++
+		for _, hash := range [][]byte{hashes[0], hashes[1], hashes[1], same, hashes[2]} {
+			if err = writer.Add(hash); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+		}
+		if err = writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if writer.Count() != 3 {
+			t.Errorf("записано %d префиксов, ожидалось 3", writer.Count())
+		}
+		if want := corpusHeaderSize + 3*4; buf.Len() != want {
+			t.Errorf("размер файла %d байт, ожидалось %d", buf.Len(), want)
+		}
+	})
+
+	t.Run("некорректная длина хеша", func(t *testing.T) {
+		writer, err := NewCorpusWriter(&bytes.Buffer{}, DefaultCorpusPrefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = writer.Add(hashes[0][:16]); err == nil {
+			t.Error("хеш длиной 16 байт принят")
+		}
+	})
+
+	t.Run("некорректная длина префикса", func(t *testing.T) {
+		for _, prefixSize := range []int{0, 3, sha1.Size + 1} {
+			if _, err := NewCorpusWriter(&bytes.Buffer{}, prefixSize); err == nil {
+				t.Errorf("длина префикса %d принята", prefixSize)
+			}
+		}
+	})
+}
+
+// TestFixturePasswords проверяет, что фикстура содержит хеши именно паролей из fixturePasswords.
+func TestFixturePasswords(t *testing.T) {
+	hashes := fixtureHashes(t)
+	if len(hashes) != len(fixturePasswords) {
+		t.Fatalf("в фикстуре %d хешей, паролей %d", len(hashes), len(fixturePasswords))
+	}
+
+	for _, password := range fixturePasswords {
+		sum := sha1.Sum([]byte(password))
+
+		found := false
+		for _, hash := range hashes {
+			found = found || bytes.Equal(hash, sum[:])
+		}
+		if !found {
+			t.Errorf("пароль %q отсутствует в фикстуре", password)
+		}
+	}
+}
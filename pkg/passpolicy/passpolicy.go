@@ -0,0 +1,89 @@
+package passpolicy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultMinScore — минимальная оценка стойкости пароля по умолчанию (по шкале от 0 до 4).
+const DefaultMinScore = 3
+
+var (
+	// ErrCorpusUnavailable возвращается, если базу утёкших паролей не удалось прочитать.
+	ErrCorpusUnavailable = errors.New("база утёкших паролей недоступна")
+
+	errWeakPassword     = errors.New("пароль слишком простой")
+	errBreachedPassword = errors.New("пароль найден в базе утёкших паролей, выберите другой")
+)
+
+// Policy проверяет пароль на стойкость и на наличие в локальной базе утёкших паролей.
+type Policy struct {
+	minScore int
+	breached *Corpus
+}
+
+// NewPolicy создаёт политику паролей. Если breached равен nil, проверка по базе утёкших паролей не выполняется.
+func NewPolicy(minScore int, breached *Corpus) *Policy {
+	return &Policy{
+		minScore: minScore,
+		breached: breached,
+	}
+}
+
+// LoadFromEnv создаёт политику паролей по переменным окружения: PASSWORD_MIN_SCORE (0–4, по умолчанию 3)
+// и PASSWORD_BREACH_CORPUS (путь к файлу базы утёкших паролей, создаётся утилитой cmd/breach-corpus).
+func LoadFromEnv() (*Policy, error) {
+	minScore := DefaultMinScore
+	if value := os.Getenv("PASSWORD_MIN_SCORE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 4 {
+			return nil, fmt.Errorf("некорректное значение PASSWORD_MIN_SCORE: %q", value)
+		}
+		minScore = n
+	}
+
+	var corpus *Corpus
+	if path := os.Getenv("PASSWORD_BREACH_CORPUS"); path != "" {
+		var err error
+		if corpus, err = OpenCorpus(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPolicy(minScore, corpus), nil
+}
+
+// Check проверяет пароль. userInputs — данные пользователя (email, имя, телефон),
+// которые не должны лежать в основе пароля.
+func (p *Policy) Check(password string, userInputs ...string) error {
+	result := Estimate(password, userInputs...)
+	if result.Score < p.minScore {
+		if result.Warning != "" {
+			return fmt.Errorf("%w: %s", errWeakPassword, result.Warning)
+		}
+		return errWeakPassword
+	}
+
+	if p.breached != nil {
+		found, err := p.breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorpusUnavailable, err)
+		}
+		if found {
+			return errBreachedPassword
+		}
+	}
+
+	return nil
+}
+
+// Close закрывает файл базы утёкших паролей.
+func (p *Policy) Close() error {
+	if p.breached == nil {
+		return nil
+	}
+
+	return p.breached.Close()
+}
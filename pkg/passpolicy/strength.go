@@ -0,0 +1,493 @@
+package passpolicy
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxEstimateLength — сколько первых символов пароля учитывается при оценке стойкости.
+// Хвост длинного пароля считается перебором, чтобы оценка не зависела квадратично от длины.
+const maxEstimateLength = 64
+
+// Result — оценка стойкости пароля в духе zxcvbn: пароль разбивается на фрагменты
+// (словарные слова, последовательности, повторы, даты), для каждого оценивается число попыток подбора.
+type Result struct {
+	Score   int     // Оценка от 0 (подбирается мгновенно) до 4 (очень стойкий)
+	Guesses float64 // log10 оценки числа попыток, нужных для подбора
+	Warning string  // Чем пароль слаб (пусто, если слабых мест не найдено)
+}
+
+// match — найденный в пароле шаблон, покрывающий символы [i, j).
+type match struct {
+	i, j    int
+	guesses float64 // log10 числа попыток для фрагмента
+	warning string
+}
+
+const (
+	warnCommon   = "пароль содержит распространённое слово или пароль"
+	warnPersonal = "пароль основан на ваших личных данных"
+	warnRepeat   = "пароль содержит повторяющиеся символы"
+	warnSequence = "пароль содержит последовательность символов (abc, 123)"
+	warnKeyboard = "пароль содержит последовательность клавиш на клавиатуре"
+	warnDate     = "пароль содержит дату или год"
+	warnShort    = "пароль слишком короткий, добавьте ещё несколько слов или символов"
+)
+
+var (
+	// Ряды клавиатуры (латинская и русская раскладки) для поиска последовательностей клавиш
+	keyboardRows = []string{
+		"1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./",
+		"йцукенгшщзхъ", "фывапролджэ", "ячсмитьбю",
+		"1qaz", "2wsx", "3edc", "4rfv", "5tgb", "6yhn", "7ujm", "8ik,", "9ol.", "0p;/",
+	}
+
+	dateDMY = regexp.MustCompile(`^(\d{1,2})([./-]?)(\d{1,2})([./-]?)(\d{2}|\d{4})$`)
+	dateYMD = regexp.MustCompile(`^(\d{4})([./-]?)(\d{1,2})([./-]?)(\d{1,2})$`)
+)
+
+// Estimate оценивает стойкость пароля. userInputs — данные пользователя (email, имя, телефон),
+// их фрагменты в пароле считаются угадываемыми так же, как самые распространённые слова.
+func Estimate(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Result{Warning: warnShort}
+	}
+
+	var tail float64
+	if len(runes) > maxEstimateLength {
+		for _, r := range runes[maxEstimateLength:] {
+			tail += math.Log10(cardinality(r))
+		}
+		runes = runes[:maxEstimateLength]
+	}
+
+	guesses, longest := estimate(runes, personalWords(userInputs), true)
+
+	result := Result{Guesses: guesses + tail}
+	result.Score = score(result.Guesses)
+	switch {
+	case longest != nil:
+		result.Warning = longest.warning
+	case result.Score < 4:
+		result.Warning = warnShort
+	}
+
+	return result
+}
+
+// estimate ищет разбиение пароля на фрагменты с минимальным суммарным числом попыток (log10)
+// и возвращает его вместе с самым длинным шаблоном разбиения. Символы, не вошедшие ни в один шаблон,
+// подбираются перебором. Повторы ищутся только на верхнем уровне, чтобы оценка фрагмента
+// повтора не уходила в рекурсию.
+func estimate(runes []rune, personal map[string]int, repeats bool) (float64, *match) {
+	lower := []rune(strings.ToLower(string(runes)))
+	matches := findMatches(runes, lower, personal, repeats)
+
+	n := len(runes)
+	best := make([]float64, n+1)
+	via := make([]*match, n+1)
+	for j := 1; j <= n; j++ {
+		best[j] = best[j-1] + math.Log10(cardinality(runes[j-1]))
+
+		for k := range matches {
+			m := &matches[k]
+			if m.j == j && best[m.i]+m.guesses < best[j] {
+				best[j] = best[m.i] + m.guesses
+				via[j] = m
+			}
+		}
+	}
+
+	// Предупреждение берём по самому длинному шаблону из найденного разбиения
+	var longest *match
+	for j := n; j > 0; {
+		if m := via[j]; m != nil {
+			if longest == nil || m.j-m.i > longest.j-longest.i {
+				longest = m
+			}
+			j = m.i
+			continue
+		}
+		j--
+	}
+
+	return best[n], longest
+}
+
+// score переводит log10 числа попыток в оценку от 0 до 4 (пороги как в zxcvbn).
+func score(guesses float64) int {
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// cardinality — число вариантов символа при переборе (размер его класса символов).
+func cardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case unicode.Is(unicode.Cyrillic, r):
+		return 33
+	default:
+		return 33
+	}
+}
+
+func findMatches(runes, lower []rune, personal map[string]int, repeats bool) []match {
+	var matches []match
+
+	matches = append(matches, dictionaryMatches(runes, lower, personal)...)
+	if repeats {
+		matches = append(matches, repeatMatches(runes, lower, personal)...)
+	}
+	matches = append(matches, sequenceMatches(lower)...)
+	matches = append(matches, keyboardMatches(lower)...)
+	matches = append(matches, dateMatches(lower)...)
+
+	return matches
+}
+
+// personalWords разбивает данные пользователя на слова: email, имя и т.п. целиком и по частям,
+// номер телефона — целиком и последние 10 и 7 цифр.
+func personalWords(userInputs []string) map[string]int {
+	words := make(map[string]int)
+	add := func(word string) {
+		if len([]rune(word)) >= 3 {
+			if _, ok := words[word]; !ok {
+				words[word] = len(words) + 1
+			}
+		}
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		add(input)
+
+		for _, part := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			add(part)
+		}
+
+		digits := strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, input)
+		for _, size := range []int{len(digits), 10, 7} {
+			if size <= len(digits) {
+				add(digits[len(digits)-size:])
+			}
+		}
+	}
+
+	return words
+}
+
+// dictionaryMatches ищет распространённые слова и данные пользователя, в том числе
+// записанные задом наперёд и с заменой букв похожими символами (p@ssw0rd).
+func dictionaryMatches(runes, lower []rune, personal map[string]int) []match {
+	var matches []match
+
+	for i := 0; i < len(lower); i++ {
+		for j := i + 3; j <= len(lower); j++ {
+			word := string(lower[i:j])
+			variations := math.Log10(caseVariations(runes[i:j]))
+
+			for _, candidate := range wordVariants(word) {
+				rank, warning, ok := lookupWord(candidate.word, personal)
+				if !ok {
+					continue
+				}
+				matches = append(matches, match{
+					i:       i,
+					j:       j,
+					guesses: math.Log10(float64(rank)) + variations + candidate.extra,
+					warning: warning,
+				})
+			}
+		}
+	}
+
+	return matches
+}
+
+type wordVariant struct {
+	word  string
+	extra float64 // log10 множителя числа попыток за преобразование
+}
+
+// wordVariants возвращает слово как есть, задом наперёд и с обратной заменой «leet»-символов.
+func wordVariants(word string) []wordVariant {
+	variants := []wordVariant{{word: word}}
+	if reversed := reverse(word); reversed != word {
+		variants = append(variants, wordVariant{word: reversed, extra: math.Log10(2)})
+	}
+
+	hasLetter := strings.IndexFunc(word, unicode.IsLetter) >= 0
+	for _, table := range leetTables {
+		unleeted, subs := unleet(word, table)
+		if subs > 0 && hasLetter {
+			variants = append(variants, wordVariant{word: unleeted, extra: math.Log10(binomial(len([]rune(word)), subs) + 1)})
+		}
+	}
+
+	return variants
+}
+
+func lookupWord(word string, personal map[string]int) (int, string, bool) {
+	if rank, ok := personal[word]; ok {
+		return rank, warnPersonal, true
+	}
+	if rank, ok := commonRanks[word]; ok {
+		return rank, warnCommon, true
+	}
+
+	return 0, "", false
+}
+
+// caseVariations — во сколько раз больше попыток нужно из-за заглавных букв в слове.
+func caseVariations(word []rune) float64 {
+	var upper, letters int
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case upper == letters, upper == 1 && unicode.IsUpper(word[0]), upper == 1 && unicode.IsUpper(word[len(word)-1]):
+		// Всё слово заглавными или заглавная первая/последняя буква — самые частые варианты
+		return 2
+	default:
+		return binomial(letters, upper)
+	}
+}
+
+// repeatMatches ищет повторы одного символа (aaa) и повторы фрагмента (abcabc).
+func repeatMatches(runes, lower []rune, personal map[string]int) []match {
+	var matches []match
+	baseCache := make(map[string]float64)
+
+	for i := 0; i < len(lower); i++ {
+		for size := 1; i+size*2 <= len(lower); size++ {
+			base := lower[i : i+size]
+			count := 1
+			for i+(count+1)*size <= len(lower) && string(lower[i+count*size:i+(count+1)*size]) == string(base) {
+				count++
+			}
+			// Повтор одного символа считаем от трёх раз, повтор фрагмента — от двух
+			if count < 2 || size == 1 && count < 3 {
+				continue
+			}
+
+			baseGuesses := math.Log10(cardinality(base[0]))
+			if size > 1 {
+				key := string(runes[i : i+size])
+				cached, ok := baseCache[key]
+				if !ok {
+					cached, _ = estimate(runes[i:i+size], personal, false)
+					baseCache[key] = cached
+				}
+				baseGuesses = cached
+			}
+			matches = append(matches, match{
+				i:       i,
+				j:       i + count*size,
+				guesses: baseGuesses + math.Log10(float64(count)),
+				warning: warnRepeat,
+			})
+		}
+	}
+
+	return matches
+}
+
+// sequenceMatches ищет последовательности символов с постоянным шагом (abc, 9753, zyx).
+func sequenceMatches(lower []rune) []match {
+	var matches []match
+
+	for i := 0; i+2 < len(lower); {
+		delta := lower[i+1] - lower[i]
+		j := i + 1
+		for j < len(lower) && lower[j]-lower[j-1] == delta && sameClass(lower[j], lower[i]) {
+			j++
+		}
+
+		if j-i >= 3 && delta != 0 && delta >= -5 && delta <= 5 {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("az09аяё", lower[i]) || lower[i] == '1':
+				base = 4
+			case unicode.IsDigit(lower[i]):
+				base = 10
+			}
+			guesses := math.Log10(base * float64(j-i))
+			if delta < 0 {
+				guesses += math.Log10(2)
+			}
+			if delta != 1 && delta != -1 {
+				guesses += math.Log10(5)
+			}
+			matches = append(matches, match{i: i, j: j, guesses: guesses, warning: warnSequence})
+		}
+
+		if j-i >= 3 {
+			i = j - 1
+		} else {
+			i++
+		}
+	}
+
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	return unicode.IsDigit(a) == unicode.IsDigit(b) && unicode.IsLetter(a) == unicode.IsLetter(b)
+}
+
+// keyboardMatches ищет последовательности соседних клавиш в одном ряду клавиатуры (qwerty, 1qaz).
+func keyboardMatches(lower []rune) []match {
+	var matches []match
+
+	for i := 0; i < len(lower); i++ {
+		longest := 0
+		for j := i + 4; j <= len(lower); j++ {
+			fragment := string(lower[i:j])
+			if !onKeyboardRow(fragment) {
+				break
+			}
+			longest = j - i
+		}
+
+		if longest > 0 {
+			matches = append(matches, match{
+				i:       i,
+				j:       i + longest,
+				guesses: math.Log10(50 * float64(longest)),
+				warning: warnKeyboard,
+			})
+		}
+	}
+
+	return matches
+}
+
+func onKeyboardRow(fragment string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, fragment) || strings.Contains(row, reverse(fragment)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dateMatches ищет годы (1950–2049) и даты (ддммгггг, дд.мм.гг, гггг-мм-дд).
+func dateMatches(lower []rune) []match {
+	var matches []match
+	now := time.Now().Year()
+
+	for i := 0; i < len(lower); i++ {
+		for j := i + 4; j <= len(lower) && j-i <= 10; j++ {
+			fragment := string(lower[i:j])
+
+			if j-i == 4 {
+				if year, err := strconv.Atoi(fragment); err == nil && year >= 1950 && year <= 2049 {
+					matches = append(matches, match{i: i, j: j, guesses: math.Log10(yearSpace(year, now)), warning: warnDate})
+				}
+				continue
+			}
+
+			if year, ok := parseDate(fragment); ok {
+				guesses := math.Log10(365 * yearSpace(year, now))
+				if strings.ContainsAny(fragment, "./-") {
+					guesses += math.Log10(4)
+				}
+				matches = append(matches, match{i: i, j: j, guesses: guesses, warning: warnDate})
+			}
+		}
+	}
+
+	return matches
+}
+
+// yearSpace — сколько лет приходится перебрать, чтобы дойти до указанного года (не меньше 20).
+func yearSpace(year, now int) float64 {
+	return math.Max(math.Abs(float64(year-now)), 20)
+}
+
+// parseDate разбирает дату в форматах день-месяц-год и год-месяц-день и возвращает год.
+func parseDate(fragment string) (int, bool) {
+	var day, month, year int
+
+	if parts := dateYMD.FindStringSubmatch(fragment); parts != nil && parts[2] == parts[4] {
+		year, _ = strconv.Atoi(parts[1])
+		month, _ = strconv.Atoi(parts[3])
+		day, _ = strconv.Atoi(parts[5])
+	} else if parts = dateDMY.FindStringSubmatch(fragment); parts != nil && parts[2] == parts[4] {
+		day, _ = strconv.Atoi(parts[1])
+		month, _ = strconv.Atoi(parts[3])
+		year, _ = strconv.Atoi(parts[5])
+		if len(parts[5]) == 2 {
+			// Двузначный год: 50–99 относим к XX веку, остальные к XXI
+			if year >= 50 {
+				year += 1900
+			} else {
+				year += 2000
+			}
+		}
+	} else {
+		return 0, false
+	}
+
+	if day < 1 || day > 31 || month < 1 || month > 12 || year < 1900 || year > 2099 {
+		return 0, false
+	}
+
+	return year, true
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
+
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
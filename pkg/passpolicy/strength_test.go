@@ -0,0 +1,107 @@
+package passpolicy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		maxScore   int // Оценка не выше
+		minScore   int // Оценка не ниже
+		warning    string
+	}{
+		{"пустой пароль", "", nil, 0, 0, warnShort},
+		{"короткий пароль", "zq", nil, 0, 0, warnShort},
+		{"распространённый пароль", "password", nil, 0, 0, warnCommon},
+		{"замена букв символами", "P@ssw0rd", nil, 0, 0, warnCommon},
+		{"слово задом наперёд", "drowssap", nil, 0, 0, warnCommon},
+		{"слово с годом", "Shadow2019", nil, 0, 0, warnCommon},
+		{"клавиши подряд", "qwertyuiop", nil, 0, 0, warnKeyboard},
+		{"повтор символа", "aaaaaaaaaaaa", nil, 0, 0, warnRepeat},
+		{"повтор фрагмента", "abcabcabcabc", nil, 0, 0, warnRepeat},
+		{"последовательность", "abcdefgh", nil, 0, 0, warnSequence},
+		{"дата", "19.05.1987", nil, 1, 0, warnDate},
+		{"телефон пользователя", "89161234567", []string{"+79161234567"}, 0, 0, warnPersonal},
+		{"случайные символы", "Kx9#mQ2$vL7!pR", nil, 4, 4, ""},
+		{"фраза из слов", "correct horse battery staple", nil, 4, 4, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Estimate(tt.password, tt.userInputs...)
+
+			if result.Score < tt.minScore || result.Score > tt.maxScore {
+				t.Errorf("оценка %d, ожидалась от %d до %d (log10 попыток %.2f)", result.Score, tt.minScore, tt.maxScore, result.Guesses)
+			}
+			if result.Warning != tt.warning {
+				t.Errorf("предупреждение %q, ожидалось %q", result.Warning, tt.warning)
+			}
+		})
+	}
+}
+
+func TestEstimatePersonalData(t *testing.T) {
+	// Данные пользователя в пароле подбираются быстрее, чем произвольные слова
+	without := Estimate("ivan1987")
+	with := Estimate("ivan1987", "ivan@example.com", "Иван")
+	if with.Guesses >= without.Guesses {
+		t.Errorf("с данными пользователя log10 попыток %.2f, без них %.2f: ожидалось меньше", with.Guesses, without.Guesses)
+	}
+}
+
+func TestEstimateLongPassword(t *testing.T) {
+	// Хвост длиннее maxEstimateLength оценивается перебором и только увеличивает оценку
+	base := strings.Repeat("Kx9#mQ2$vL7!pR", 5)[:maxEstimateLength]
+	short := Estimate(base)
+	long := Estimate(base + "tail")
+	if long.Guesses <= short.Guesses {
+		t.Errorf("log10 попыток для длинного пароля %.2f, для его начала %.2f", long.Guesses, short.Guesses)
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		want    int
+	}{
+		{0, 0}, {2.99, 0}, {3, 1}, {5.99, 1}, {6, 2}, {7.99, 2}, {8, 3}, {9.99, 3}, {10, 4}, {40, 4},
+	}
+
+	for _, tt := range tests {
+		if got := score(tt.guesses); got != tt.want {
+			t.Errorf("score(%v) = %d, ожидалось %d", tt.guesses, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := NewPolicy(DefaultMinScore, buildCorpus(t, fixtureHashes(t), DefaultCorpusPrefix))
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"слабый пароль", "password", errWeakPassword},
+		{"стойкий, но утёкший пароль", "Kx9#mQ2$vL7!pR", errBreachedPassword},
+		{"стойкий пароль", "correct horse battery staple", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.Check(tt.password, "user@example.com"); !errors.Is(err, tt.want) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+
+	// Без базы утёкших паролей проверяется только стойкость
+	if err := NewPolicy(DefaultMinScore, nil).Check("Kx9#mQ2$vL7!pR"); err != nil {
+		t.Errorf("без базы утёкших паролей: %v", err)
+	}
+}
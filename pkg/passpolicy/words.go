@@ -0,0 +1,54 @@
+package passpolicy
+
+import "strings"
+
+// leetTables — замены букв похожими символами. Для «1» и «!» возможны два варианта: i и l.
+var leetTables = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '9': 'g', '1': 'l', '!': 'l', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+}
+
+// unleet заменяет «leet»-символы буквами и возвращает число замен.
+func unleet(word string, table map[rune]rune) (string, int) {
+	subs := 0
+	result := strings.Map(func(r rune) rune {
+		if letter, ok := table[r]; ok {
+			subs++
+			return letter
+		}
+		return r
+	}, word)
+
+	return result, subs
+}
+
+// commonWords — самые распространённые пароли и слова из них, по убыванию частоты.
+// Позиция слова в списке используется как оценка числа попыток для его подбора.
+var commonWords = strings.Fields(`
+password 123456 qwerty 12345678 111111 iloveyou admin welcome monkey dragon
+letmein football baseball master shadow sunshine princess superman batman trustno1
+hello freedom whatever login starwars passw0rd pass qazwsx michael jennifer
+hunter killer charlie soccer hockey ranger buster thomas tigger robert daniel
+jordan andrew harley summer winter spring autumn flower computer internet secret
+access love lovely angel angels cookie cheese chocolate coffee pepper orange
+banana apple yellow purple silver golden diamond money family friend friends
+forever nicole jessica ashley michelle pokemon naruto matrix mustang corvette
+ferrari porsche mercedes samsung google facebook youtube twitter yandex mail
+user guest test demo root default changeme service server system manager
+parol privet lubov lyubov kotik solnce natasha marina svetlana sergey alexander
+andrey dmitry maxim ivan olga elena tatiana irina vladimir moskva russia rossiya
+spartak zenit dinamo lokomotiv
+пароль привет любовь люблю котик солнце наташа марина светлана сергей александр
+андрей дмитрий максим иван ольга елена татьяна ирина владимир москва россия
+food delivery pizza sushi burger order
+`)
+
+var commonRanks = func() map[string]int {
+	ranks := make(map[string]int, len(commonWords))
+	for i, word := range commonWords {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
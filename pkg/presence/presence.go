@@ -0,0 +1,64 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// Присутствие пользователя хранится в Redis отдельно от статуса аккаунта:
+//   - presence:online:<id>    — пользователь в сети, ключ живёт OnlineWindow с последней активности;
+//   - presence:last_seen:<id> — unix-время последней активности, хранится LastSeenTTL.
+
+var (
+	OnlineWindow = time.Minute * 5       // Сколько пользователь считается в сети после последней активности
+	LastSeenTTL  = (time.Hour * 24) * 90 // Сколько хранится время последней активности
+)
+
+func onlineKey(userID int) string {
+	return fmt.Sprintf("presence:online:%d", userID)
+}
+
+func lastSeenKey(userID int) string {
+	return fmt.Sprintf("presence:last_seen:%d", userID)
+}
+
+// Touch отмечает активность пользователя: он в сети, время последней активности — сейчас.
+func Touch(ctx context.Context, client *redis.Client, userID int) error {
+	pipe := client.Pipeline()
+	pipe.Set(ctx, onlineKey(userID), 1, OnlineWindow)
+	pipe.Set(ctx, lastSeenKey(userID), time.Now().Unix(), LastSeenTTL)
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+// SetOffline отмечает, что пользователь вышел из сети (например, завершил все сессии).
+// Время последней активности сохраняется.
+func SetOffline(ctx context.Context, client *redis.Client, userID int) error {
+	return client.Del(ctx, onlineKey(userID)).Err()
+}
+
+// Get возвращает, в сети ли пользователь, и время его последней активности
+// (нулевое время, если активность не зафиксирована).
+func Get(ctx context.Context, client *redis.Client, userID int) (bool, time.Time, error) {
+	pipe := client.Pipeline()
+	online := pipe.Exists(ctx, onlineKey(userID))
+	lastSeen := pipe.Get(ctx, lastSeenKey(userID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return false, time.Time{}, err
+	}
+
+	var seen time.Time
+	if value, err := lastSeen.Result(); err == nil {
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		seen = time.Unix(unix, 0)
+	}
+
+	return online.Val() == 1, seen, nil
+}
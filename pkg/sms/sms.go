@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"fmt"
+	"food-delivery/pkg/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender отправляет SMS-сообщения. Реальный провайдер подключается реализацией этого интерфейса.
+type SMSSender interface {
+	Send(phone, message string) error
+}
+
+// FileSender — фейковый провайдер для локальной разработки: вместо отправки записывает SMS в файл.
+// Если путь к файлу не задан, сообщения пишутся в лог приложения.
+type FileSender struct {
+	path string
+	log  *logger.Logger
+	mu   sync.Mutex
+}
+
+func NewFileSender(path string, log *logger.Logger) *FileSender {
+	return &FileSender{
+		path: path,
+		log:  log,
+	}
+}
+
+func (s *FileSender) Send(phone, message string) error {
+	line := fmt.Sprintf("[%s] SMS для %s: %s\n", time.Now().Format("2006-01-02 15:04:05"), phone, message)
+
+	if s.path == "" {
+		s.log.Info(line)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл SMS: %v", err)
+	}
+	defer file.Close()
+
+	if _, err = file.WriteString(line); err != nil {
+		return fmt.Errorf("не удалось записать SMS в файл: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+var errInvalidPhone = errors.New("некорректный номер телефона")
+
+// NormalizePhone приводит номер телефона к формату E.164 (+<код страны><номер>).
+// Пробелы, дефисы, скобки и точки отбрасываются. Номера без кода страны
+// (10 цифр или 11 цифр, начинающиеся с 8) считаются российскими.
+func NormalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	plus := false
+
+	for i, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			plus = true
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			// Разделители игнорируются
+		default:
+			return "", errInvalidPhone
+		}
+	}
+
+	number := digits.String()
+
+	switch {
+	case plus:
+		// Номер уже содержит код страны
+	case strings.HasPrefix(number, "00"):
+		// Международный префикс 00 эквивалентен «+»
+		number = number[2:]
+	case len(number) == 11 && (number[0] == '8' || number[0] == '7'):
+		number = "7" + number[1:]
+	case len(number) == 10:
+		number = "7" + number
+	default:
+		return "", errInvalidPhone
+	}
+
+	// E.164 допускает не более 15 цифр, код страны не начинается с нуля
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", errInvalidPhone
+	}
+
+	return "+" + number, nil
+}
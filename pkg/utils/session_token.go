@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"food-delivery/internal/auth/entities"
+	"food-delivery/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v4"
+	"os"
+	"time"
+)
+
+// minTokenHashKeyLen — минимальная длина ключа TOKEN_HASH_KEY в байтах.
+const minTokenHashKeyLen = 32
+
+var (
+	errTokenHashKeyMissing = errors.New("не задан ключ TOKEN_HASH_KEY")
+	errTokenHashKeyShort   = errors.New("ключ TOKEN_HASH_KEY должен быть не короче 32 байт")
+)
+
+// tokenHashKey — ключ HMAC для хеширования токенов, загружается при старте приложения.
+var tokenHashKey []byte
+
+// LoadTokenHashKey читает ключ хеширования токенов из переменной окружения TOKEN_HASH_KEY.
+// Без ключа (или со слишком коротким ключом) приложение не должно запускаться:
+// иначе хеши токенов в базе данных можно было бы подобрать по утёкшей таблице.
+func LoadTokenHashKey() error {
+	key := os.Getenv("TOKEN_HASH_KEY")
+	if key == "" {
+		return errTokenHashKeyMissing
+	}
+	if len(key) < minTokenHashKeyLen {
+		return errTokenHashKeyShort
+	}
+
+	tokenHashKey = []byte(key)
+	return nil
+}
+
+// GenRandToken генерирует криптографически стойкую случайную строку из n байт в hex-представлении.
+// Используется для ID сессий и идентификаторов токенов (jti).
+func GenRandToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateSessionAccessToken генерирует access токен, привязанный к сессии устройства.
+// Токен содержит уникальный идентификатор (jti) и время выпуска (iat), по которым его можно отозвать
+// до истечения срока действия.
+func GenerateSessionAccessToken(user *entities.User, sessionID string, ttl time.Duration) (string, error) {
+	jti, err := GenRandToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := entities.AccessClaim{
+		ID:         user.ID,
+		Email:      user.Email,
+		Role:       user.Role,
+		Type:       entities.TokenTypeAccess,
+		SessionID:  sessionID,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	// Подписываем токен активным ключом, kid попадает в заголовок токена
+	return jwtkeys.Default().Sign(claims)
+}
+
+// GenerateSessionRefreshToken генерирует refresh токен, привязанный к сессии устройства.
+// Помимо ID пользователя токен содержит ID сессии (sid) и собственный уникальный идентификатор (jti),
+// поэтому два токена, выпущенных в одну и ту же секунду, никогда не совпадут.
+func GenerateSessionRefreshToken(user *entities.User, sessionID string, ttl time.Duration) (string, time.Time, error) {
+	jti, err := GenRandToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := entities.RefreshClaim{
+		ID:        user.ID,
+		Type:      entities.TokenTypeRefresh,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	// Подписываем токен тем же ключом, что и остальные токены приложения
+	signed, err := jwtkeys.Default().Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
+// HashToken возвращает ключевой хеш (HMAC-SHA256) токена в hex-представлении.
+// В базе данных хранится только хеш, поэтому утечка таблицы tokens не позволяет воспользоваться сессиями.
+// Ключ должен быть предварительно загружен через LoadTokenHashKey.
+func HashToken(token string) string {
+	if len(tokenHashKey) == 0 {
+		panic(errTokenHashKeyMissing)
+	}
+
+	mac := hmac.New(sha256.New, tokenHashKey)
+	mac.Write([]byte(token))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Типы устройств, определяемые по User-Agent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+var (
+	androidVersion = regexp.MustCompile(`Android (\d+(?:\.\d+)?)`)
+	iosVersion     = regexp.MustCompile(`OS (\d+)[_.](\d+)`)
+	windowsVersion = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+	macVersion     = regexp.MustCompile(`Mac OS X (\d+)[_.](\d+)`)
+)
+
+// Соответствие версий ядра Windows NT маркетинговым названиям
+var windowsNames = map[string]string{
+	"10.0": "Windows 10/11",
+	"6.3":  "Windows 8.1",
+	"6.2":  "Windows 8",
+	"6.1":  "Windows 7",
+}
+
+// ParseUserAgent определяет тип устройства и операционную систему по заголовку User-Agent.
+// Разбор упрощённый и рассчитан на отображение истории входов, а не на точную идентификацию.
+func ParseUserAgent(userAgent string) (device, os string) {
+	if userAgent == "" {
+		return DeviceUnknown, ""
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "crawler") || strings.Contains(ua, "spider"):
+		device = DeviceBot
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		device = DeviceTablet
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		device = DeviceMobile
+	case strings.Contains(ua, "windows") || strings.Contains(ua, "macintosh") || strings.Contains(ua, "x11") ||
+		strings.Contains(ua, "cros"):
+		device = DeviceDesktop
+	default:
+		device = DeviceUnknown
+	}
+
+	switch {
+	case strings.Contains(ua, "android"):
+		os = "Android"
+		if m := androidVersion.FindStringSubmatch(userAgent); m != nil {
+			os += " " + m[1]
+		}
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad") || strings.Contains(ua, "ipod"):
+		os = "iOS"
+		if m := iosVersion.FindStringSubmatch(userAgent); m != nil {
+			os += " " + m[1] + "." + m[2]
+		}
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+		if m := windowsVersion.FindStringSubmatch(userAgent); m != nil {
+			if name, ok := windowsNames[m[1]]; ok {
+				os = name
+			}
+		}
+	case strings.Contains(ua, "mac os x"):
+		os = "macOS"
+		if m := macVersion.FindStringSubmatch(userAgent); m != nil {
+			os += " " + m[1] + "." + m[2]
+		}
+	case strings.Contains(ua, "cros"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	return device, os
+}